@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"expandourhouse.com/api/store"
+)
+
+/*
+A state's aggregates are sums of its districts' facts.  The populations are
+ACS estimates, so their sums get margins of error by the Census Bureau's
+approximation for sums of estimates:
+
+	MOE(sum) = sqrt(MOE(1)^2 + MOE(2)^2 + ...)
+
+and people per representative is the population divided by the (exact)
+number of districts that it was summed over, so its MOE is the
+population's MOE divided by the same number.  (Dividing an incomplete
+population by all the representatives would understate it.)
+
+Irregular states get aggregates too, from all of their districts, even
+though we don't list their districts.  If some districts lack a fact, its
+aggregate is the sum over the districts that have it, and is marked as
+incomplete.
+*/
+
+// aggregate is a number about a state, derived from its districts' facts.
+type aggregate struct {
+	Value         float64  `json:"value"`
+	MarginOfError *float64 `json:"marginOfError"` /* nil == exact */
+	Sources       []string `json:"sources"`
+	NbrDistricts  int      `json:"nbrDistricts"` /* that have the fact */
+	Complete      bool     `json:"complete"`     /* all districts have the fact */
+}
+
+type stateAggregates struct {
+	NbrReps      int        `json:"nbrReps"`
+	Population   *aggregate `json:"population"` /* nil == unknown */
+	Adults       *aggregate `json:"adults"`
+	Citizens     *aggregate `json:"citizens"`
+	CVAP         *aggregate `json:"cvap"`
+	Turnout      *aggregate `json:"turnout"`
+	PeoplePerRep *aggregate `json:"peoplePerRep"`
+}
+
+// sumFacts adds up a fact over the given districts.  It returns nil if no
+// district has the fact.
+func sumFacts(districts []store.District, allFacts map[store.District]districtFacts,
+	factType string) *aggregate {
+
+	var result aggregate
+	var sumSquaredMoes float64
+	hasMoe := false
+	sources := make(map[string]bool)
+	for _, d := range districts {
+		f := factOf(allFacts[d], factType)
+		if f == nil {
+			continue
+		}
+		if withMoe, ok := allFacts[d][factType].(*factWithMoe); ok {
+			moe := float64(withMoe.MarginOfError)
+			sumSquaredMoes += moe * moe
+			hasMoe = true
+		}
+		result.Value += float64(f.Value)
+		result.NbrDistricts++
+		sources[f.Source] = true
+	}
+	if result.NbrDistricts == 0 {
+		return nil
+	}
+
+	result.Complete = result.NbrDistricts == len(districts)
+	if hasMoe {
+		moe := math.Sqrt(sumSquaredMoes)
+		result.MarginOfError = &moe
+	}
+	result.Sources = make([]string, 0, len(sources))
+	for source := range sources {
+		result.Sources = append(result.Sources, source)
+	}
+	sort.Strings(result.Sources)
+	return &result
+}
+
+// aggregateState returns the aggregates for a state with the given
+// districts.
+func aggregateState(districts []store.District,
+	allFacts map[store.District]districtFacts) *stateAggregates {
+
+	result := stateAggregates{
+		NbrReps:    len(districts),
+		Population: sumFacts(districts, allFacts, "all"),
+		Adults:     sumFacts(districts, allFacts, "adults"),
+		Citizens:   sumFacts(districts, allFacts, "citizens"),
+		CVAP:       sumFacts(districts, allFacts, "cvap"),
+		Turnout:    sumFacts(districts, allFacts, "turnout"),
+	}
+
+	// compute people per rep
+	if result.Population != nil {
+		perRep := *result.Population
+		nbrDistricts := float64(perRep.NbrDistricts)
+		perRep.Value /= nbrDistricts
+		if perRep.MarginOfError != nil {
+			moe := *perRep.MarginOfError / nbrDistricts
+			perRep.MarginOfError = &moe
+		}
+		result.PeoplePerRep = &perRep
+	}
+	return &result
+}
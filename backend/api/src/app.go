@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+For small deployments and demos, the API can also serve the app (i.e., the
+React build in app/build), so that one server is the whole product.
+
+Paths that aren't files get the app's index.html, so that the app can do its
+own routing.  The build's hashed assets (e.g., static/js/main.c33929f2.chunk.js)
+never change, so clients may cache them forever; everything else must be
+revalidated.  If the build has precompressed files (e.g., main.js.br or
+main.js.gz), we send them to clients that accept them.
+*/
+
+const gAppIndexFile = "index.html"
+
+// gHashedAssetPattern matches the names of files with content hashes.
+var gHashedAssetPattern = regexp.MustCompile(`\.[0-9a-f]{8,}\.`)
+
+type precompression struct {
+	encoding  string
+	extension string
+}
+
+/* In order of preference */
+var gPrecompressions = []precompression{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+type appHandler struct {
+	dir string
+}
+
+func newAppHandler(dir string) *appHandler {
+	return &appHandler{dir: dir}
+}
+
+// acceptsEncoding returns whether the client accepts the given content
+// encoding.
+func acceptsEncoding(req *http.Request, encoding string) bool {
+	for _, part := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(fields[0]) != encoding {
+			continue
+		}
+		/* "br;q=0" means not acceptable */
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// serveFile sends the file at the given path (relative to the app's dir),
+// or a precompressed version of it.  It returns false if there is no such
+// file.
+func (self *appHandler) serveFile(resp http.ResponseWriter, req *http.Request,
+	relPath string) bool {
+
+	fullPath := filepath.Join(self.dir, filepath.FromSlash(relPath))
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	// set cache policy
+	name := path.Base(relPath)
+	if gHashedAssetPattern.MatchString(name) {
+		resp.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		resp.Header().Set("Cache-Control", "no-cache")
+	}
+	resp.Header().Add("Vary", "Accept-Encoding")
+
+	// find precompressed version
+	servePath := fullPath
+	for _, pc := range gPrecompressions {
+		if !acceptsEncoding(req, pc.encoding) {
+			continue
+		}
+		if pcInfo, err := os.Stat(fullPath + pc.extension); err == nil && !pcInfo.IsDir() {
+			servePath = fullPath + pc.extension
+			info = pcInfo
+			resp.Header().Set("Content-Encoding", pc.encoding)
+			break
+		}
+	}
+
+	f, err := os.Open(servePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	/* ServeContent gets the content type from the (uncompressed) file's name */
+	http.ServeContent(resp, req, name, info.ModTime(), f)
+	return true
+}
+
+func (self *appHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	/* Unknown API paths get JSON errors, not the app */
+	relPath := path.Clean("/" + req.URL.Path)
+	if relPath == "/api" || strings.HasPrefix(relPath, "/api/") {
+		gNotFoundHandler.ServeHTTP(resp, req)
+		return
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		gMethodNotAllowedHandler.ServeHTTP(resp, req)
+		return
+	}
+
+	if relPath != "/" && self.serveFile(resp, req, relPath) {
+		return
+	}
+
+	/* A missing asset (e.g., "/static/js/old.js") is an error, not a page */
+	if path.Ext(relPath) != "" && path.Ext(relPath) != ".html" {
+		writeError(resp, req, errNotFound("No such file: %v", relPath))
+		return
+	}
+
+	// let the app route the path
+	if !self.serveFile(resp, req, gAppIndexFile) {
+		writeError(resp, req, errNotFound("The app has not been built"))
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"expandourhouse.com/lib/apportionment"
+)
+
+// gMaxHouseSize bounds the House sizes we are willing to compute.
+const gMaxHouseSize = 10000
+
+// stateApportionment is a state's part of an apportionment.  The response
+// has the same states as handleGetStates's, each with its apportionment.
+type stateApportionment struct {
+	Population   int     `json:"population"`
+	Seats        int     `json:"seats"`
+	ActualSeats  int     `json:"actualSeats"`
+	Change       int     `json:"change"`
+	PeoplePerRep float64 `json:"peoplePerRep"`
+}
+
+type apportionmentInfo struct {
+	Size             int                   `json:"size"`
+	Method           apportionment.Method  `json:"method"`
+	PopulationSource string                `json:"populationSource"`
+	Tied             []string              `json:"tied,omitempty"`
+	States           map[string]*stateInfo `json:"states"`
+}
+
+func handleGetApportionment(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	size := p.queryInt("size", 0, 1, gMaxHouseSize) /* 0 == the census's size */
+	method := apportionment.HuntingtonHill
+	if s := p.queryString("method"); len(s) > 0 {
+		var err error
+		method, err = apportionment.ParseMethod(s)
+		if err != nil {
+			p.addProblem("method", "%v", err)
+		}
+	}
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireCongress(req.Context(), congress); err != nil {
+		return err
+	}
+
+	// get populations and actual seats
+	pops, err := gStore.StatePops(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+	if len(pops) == 0 {
+		return errNotFound("No census population data for congress %v", congress)
+	}
+	actualSeats, err := getActualSeats(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		/* The size to which the census's apportionment was made */
+		if year, ok := apportionment.CensusForCongress(congress); ok {
+			if h, ok := apportionment.Historical(year); ok {
+				size = h.Size
+			}
+		}
+	}
+	if size == 0 {
+		for _, seats := range actualSeats {
+			size += seats
+		}
+	}
+	states, err := getStates(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+
+	// reapportion
+	popValues := make(map[string]int)
+	sources := make(map[string]bool)
+	for state, pop := range pops {
+		popValues[state] = pop.Value
+		sources[pop.Source] = true
+	}
+	res, err := apportionment.Apportion(popValues, size, method)
+	if err != nil {
+		return errBadRequest("%v", err)
+	}
+
+	// make result
+	result := apportionmentInfo{
+		Size:             res.Size,
+		Method:           res.Method,
+		Tied:             res.Tied,
+		PopulationSource: joinSources(sources),
+		States:           states,
+	}
+	for state, seats := range res.Seats {
+		info, ok := states[state]
+		if !ok {
+			/* The state has a population but no districts */
+			info = &stateInfo{}
+			states[state] = info
+		}
+		info.Apportionment = &stateApportionment{
+			Population:   popValues[state],
+			Seats:        seats,
+			ActualSeats:  actualSeats[state],
+			Change:       seats - actualSeats[state],
+			PeoplePerRep: float64(popValues[state]) / float64(seats),
+		}
+	}
+
+	// make response
+	return writeJSON(resp, result)
+}
+
+func joinSources(sources map[string]bool) string {
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "; ")
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+/*
+The data changes only when loaddata runs, so responses are cached until the
+data version changes.  Every response gets an ETag (made from the data
+version and a hash of the body) and a Last-Modified (the time of the last
+load), so browsers and CDNs can revalidate with conditional requests.
+*/
+
+type cachedResponse struct {
+	key         string
+	contentType string
+	etag        string
+	body        []byte
+}
+
+// responseCache is an LRU cache of successful responses for one data
+// version.
+type responseCache struct {
+	mu           sync.Mutex
+	maxEntries   int /* 0 == don't cache */
+	cacheControl string
+	version      int64
+	entries      map[string]*list.Element
+	lru          *list.List /* of *cachedResponse, most recently used first */
+}
+
+func newResponseCache(maxEntries int, maxAgeSecs int) *responseCache {
+	return &responseCache{
+		maxEntries:   maxEntries,
+		cacheControl: fmt.Sprintf("public, max-age=%v", maxAgeSecs),
+		entries:      make(map[string]*list.Element),
+		lru:          list.New(),
+	}
+}
+
+func (self *responseCache) get(version int64, key string) *cachedResponse {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if version != self.version {
+		return nil
+	}
+	elem, ok := self.entries[key]
+	if !ok {
+		return nil
+	}
+	self.lru.MoveToFront(elem)
+	return elem.Value.(*cachedResponse)
+}
+
+func (self *responseCache) put(version int64, entry *cachedResponse) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.maxEntries <= 0 {
+		return
+	}
+	if version != self.version {
+		// the data changed, so everything we have is stale
+		self.entries = make(map[string]*list.Element)
+		self.lru.Init()
+		self.version = version
+	}
+	if elem, ok := self.entries[entry.key]; ok {
+		elem.Value = entry
+		self.lru.MoveToFront(elem)
+		return
+	}
+	self.entries[entry.key] = self.lru.PushFront(entry)
+	for self.lru.Len() > self.maxEntries {
+		oldest := self.lru.Back()
+		delete(self.entries, oldest.Value.(*cachedResponse).key)
+		self.lru.Remove(oldest)
+	}
+}
+
+// responseBuffer is an http.ResponseWriter that keeps the response in memory.
+type responseBuffer struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (self *responseBuffer) Header() http.Header {
+	return self.header
+}
+
+func (self *responseBuffer) WriteHeader(statusCode int) {
+	self.statusCode = statusCode
+}
+
+func (self *responseBuffer) Write(data []byte) (int, error) {
+	return self.body.Write(data)
+}
+
+func (self *responseBuffer) copyTo(resp http.ResponseWriter, req *http.Request) {
+	for name, values := range self.header {
+		resp.Header()[name] = values
+	}
+	resp.WriteHeader(self.statusCode)
+	if req.Method != http.MethodHead {
+		resp.Write(self.body.Bytes())
+	}
+}
+
+// exportResponseWriter sets an export's caching headers only if the
+// response is a success, and drops the body of a response to a HEAD request.
+type exportResponseWriter struct {
+	http.ResponseWriter
+	cacheHeaders http.Header
+	head         bool
+	wroteHeader  bool
+}
+
+func (self *exportResponseWriter) WriteHeader(statusCode int) {
+	if self.wroteHeader {
+		return
+	}
+	self.wroteHeader = true
+	if statusCode == http.StatusOK {
+		for name, values := range self.cacheHeaders {
+			self.Header()[name] = values
+		}
+	}
+	self.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (self *exportResponseWriter) Write(data []byte) (int, error) {
+	if !self.wroteHeader {
+		self.WriteHeader(http.StatusOK)
+	}
+	if self.head {
+		return len(data), nil
+	}
+	return self.ResponseWriter.Write(data)
+}
+
+func (self *responseCache) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			next.ServeHTTP(resp, req)
+			return
+		}
+
+		version, err := gStore.DataVersion(req.Context())
+		if err != nil {
+			writeError(resp, req, err)
+			return
+		}
+
+		resp.Header().Set("Vary", "Accept")
+		if requestedFormat(req) != gFormatJSON {
+			/*
+				Exports are streamed, so we can't hash or cache them.  Their
+				ETags depend only on the data version.
+			*/
+			etag := fmt.Sprintf(`W/"%v"`, version.Nbr)
+			cacheHeaders := make(http.Header)
+			cacheHeaders.Set("ETag", etag)
+			cacheHeaders.Set("Cache-Control", self.cacheControl)
+			if !version.UpdatedAt.IsZero() {
+				cacheHeaders.Set("Last-Modified", version.UpdatedAt.UTC().Format(http.TimeFormat))
+			}
+			if strings.Contains(req.Header.Get("If-None-Match"), etag) {
+				for name, values := range cacheHeaders {
+					resp.Header()[name] = values
+				}
+				resp.WriteHeader(http.StatusNotModified)
+				return
+			}
+			next.ServeHTTP(&exportResponseWriter{
+				ResponseWriter: resp,
+				cacheHeaders:   cacheHeaders,
+				head:           req.Method == http.MethodHead,
+			}, req)
+			return
+		}
+
+		key := req.URL.RequestURI()
+		entry := self.get(version.Nbr, key)
+		if entry == nil {
+			// make response
+			buf := newResponseBuffer()
+			next.ServeHTTP(buf, req)
+			if buf.statusCode != http.StatusOK {
+				buf.copyTo(resp, req)
+				return
+			}
+			body := buf.body.Bytes()
+			hash := sha256.Sum256(body)
+			entry = &cachedResponse{
+				key:         key,
+				contentType: buf.header.Get(gContentTypeHeader),
+				etag:        fmt.Sprintf(`"%v-%x"`, version.Nbr, hash[:8]),
+				body:        body,
+			}
+			self.put(version.Nbr, entry)
+		}
+
+		/*
+			ServeContent handles If-None-Match and If-Modified-Since, and
+			doesn't write the body for HEAD requests
+		*/
+		resp.Header().Set(gContentTypeHeader, entry.contentType)
+		resp.Header().Set("ETag", entry.etag)
+		resp.Header().Set("Cache-Control", self.cacheControl)
+		http.ServeContent(resp, req, "", version.UpdatedAt, bytes.NewReader(entry.body))
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"expandourhouse.com/api/store"
+)
+
+const gDefaultNbrTurnoutChanges = 10
+const gMaxNbrTurnoutChanges = 100
+
+type seatChange struct {
+	From   int `json:"from"`
+	To     int `json:"to"`
+	Change int `json:"change"`
+}
+
+type medianVotersChange struct {
+	From   *float64 `json:"from"` /* nil == unknown */
+	To     *float64 `json:"to"`
+	Change *float64 `json:"change"`
+}
+
+type turnoutChange struct {
+	State    string `json:"state"`
+	District int    `json:"district"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Change   int    `json:"change"`
+}
+
+type comparison struct {
+	From          int                    `json:"from"`
+	To            int                    `json:"to"`
+	Seats         map[string]*seatChange `json:"seats"`
+	StatesAdded   []string               `json:"statesAdded"`
+	StatesRemoved []string               `json:"statesRemoved"`
+	MedianVoters  medianVotersChange     `json:"medianVoters"`
+
+	// TurnoutChanges lists the regular districts (in both congresses) whose
+	// turnout changed the most, in descending order of the size of the change.
+	TurnoutChanges []*turnoutChange `json:"turnoutChanges"`
+}
+
+// getMedianVoters returns the median number of voters per regular district
+// in the given congress, or nil if unknown.
+func getMedianVoters(ctx context.Context, congress int) (*float64, error) {
+	voters, err := getVotersPerRegDistrict(ctx, congress)
+	if err != nil || len(voters) == 0 {
+		return nil, err
+	}
+	median := percentile(voters, 50)
+	return &median, nil
+}
+
+func compareCongresses(ctx context.Context, from, to int,
+	nbrTurnoutChanges int) (*comparison, error) {
+
+	result := comparison{
+		From:           from,
+		To:             to,
+		Seats:          make(map[string]*seatChange),
+		StatesAdded:    []string{},
+		StatesRemoved:  []string{},
+		TurnoutChanges: []*turnoutChange{},
+	}
+
+	// get states and districts
+	fromStates, err := getStates(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toStates, err := getStates(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	fromDistricts, err := getDistricts(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toDistricts, err := getDistricts(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare seats
+	for state := range fromStates {
+		if _, ok := toStates[state]; !ok {
+			result.StatesRemoved = append(result.StatesRemoved, state)
+		}
+		result.Seats[state] = &seatChange{From: len(fromDistricts[state])}
+	}
+	for state := range toStates {
+		if _, ok := fromStates[state]; !ok {
+			result.StatesAdded = append(result.StatesAdded, state)
+			result.Seats[state] = &seatChange{}
+		}
+		result.Seats[state].To = len(toDistricts[state])
+	}
+	for _, change := range result.Seats {
+		change.Change = change.To - change.From
+	}
+	sort.Strings(result.StatesAdded)
+	sort.Strings(result.StatesRemoved)
+
+	// compare median voters
+	result.MedianVoters.From, err = getMedianVoters(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	result.MedianVoters.To, err = getMedianVoters(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if result.MedianVoters.From != nil && result.MedianVoters.To != nil {
+		change := *result.MedianVoters.To - *result.MedianVoters.From
+		result.MedianVoters.Change = &change
+	}
+
+	// compare turnout in districts that are in both congresses
+	for state, fromState := range fromStates {
+		toState, ok := toStates[state]
+		if !ok {
+			continue
+		}
+		for district, fromFacts := range fromState.Districts {
+			toFacts, ok := toState.Districts[district]
+			if !ok {
+				continue
+			}
+			fromTurnout, ok1 := turnoutOf(fromFacts)
+			toTurnout, ok2 := turnoutOf(toFacts)
+			if !ok1 || !ok2 {
+				continue
+			}
+			nbr, _ := strconv.Atoi(district)
+			result.TurnoutChanges = append(result.TurnoutChanges, &turnoutChange{
+				State:    state,
+				District: nbr,
+				From:     fromTurnout,
+				To:       toTurnout,
+				Change:   toTurnout - fromTurnout,
+			})
+		}
+	}
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	sort.Slice(result.TurnoutChanges, func(i, j int) bool {
+		a, b := result.TurnoutChanges[i], result.TurnoutChanges[j]
+		if abs(a.Change) != abs(b.Change) {
+			return abs(a.Change) > abs(b.Change)
+		}
+		return districtLess(store.District{State: a.State, Nbr: a.District},
+			store.District{State: b.State, Nbr: b.District})
+	})
+	if len(result.TurnoutChanges) > nbrTurnoutChanges {
+		result.TurnoutChanges = result.TurnoutChanges[:nbrTurnoutChanges]
+	}
+
+	return &result, nil
+}
+
+func handleCompare(resp http.ResponseWriter, req *http.Request) error {
+	// get params
+	p := newParams(req)
+	from := p.requiredQueryInt("from")
+	to := p.requiredQueryInt("to")
+	nbrTurnoutChanges := p.queryInt("limit", gDefaultNbrTurnoutChanges, 0,
+		gMaxNbrTurnoutChanges)
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	// check that congresses exist
+	if err := requireCongress(req.Context(), from); err != nil {
+		return err
+	}
+	if err := requireCongress(req.Context(), to); err != nil {
+		return err
+	}
+
+	// compare
+	result, err := compareCongresses(req.Context(), from, to, nbrTurnoutChanges)
+	if err != nil {
+		return err
+	}
+
+	// make response
+	return writeJSON(resp, result)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Settings come from (in increasing order of precedence):
+	1. Defaults
+	2. A JSON config file (given by -config or EOH_API_CONFIG)
+	3. Environment variables (EOH_API_*)
+	4. Command-line flags
+*/
+
+// duration is a time.Duration that is written as a string (e.g., "15s") in
+// config files.
+type duration struct {
+	time.Duration
+}
+
+func (self *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	self.Duration = d
+	return nil
+}
+
+func (self *duration) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	self.Duration = d
+	return nil
+}
+
+type config struct {
+	// DB
+	DSN             string   `json:"dsn"`
+	SQLitePath      string   `json:"sqlitePath"`
+	MaxOpenConns    int      `json:"maxOpenConns"`
+	MaxIdleConns    int      `json:"maxIdleConns"`
+	ConnMaxLifetime duration `json:"connMaxLifetime"`
+
+	// HTTP server
+	ListenAddr      string   `json:"listenAddr"`
+	ReadTimeout     duration `json:"readTimeout"`
+	WriteTimeout    duration `json:"writeTimeout"`
+	IdleTimeout     duration `json:"idleTimeout"`
+	ShutdownTimeout duration `json:"shutdownTimeout"`
+	TLSCertFile     string   `json:"tlsCertFile"`
+	TLSKeyFile      string   `json:"tlsKeyFile"`
+
+	// caching
+	CacheEntries    int `json:"cacheEntries"`
+	CacheMaxAgeSecs int `json:"cacheMaxAgeSecs"`
+
+	// turnout sources
+	TurnoutSourcePriority []string `json:"turnoutSourcePriority"`
+	TurnoutDisagreement   float64  `json:"turnoutDisagreement"`
+
+	// readiness
+	MaxDataAge duration `json:"maxDataAge"` /* 0 == no limit */
+
+	// observability
+	AccessLog       bool   `json:"accessLog"`
+	PprofListenAddr string `json:"pprofListenAddr"` /* "" == no pprof */
+
+	// AppDir, if set, is the app's build directory (e.g., app/build), which
+	// we then serve at every path that isn't the API's.
+	AppDir string `json:"appDir"`
+
+	// StaticExportDir, if set, makes us write a static export of the API to
+	// this directory and exit instead of serving.
+	StaticExportDir string `json:"staticExportDir"`
+}
+
+func defaultConfig() config {
+	return config{
+		DSN: "host=db user=postgres password=pw dbname=house" +
+			" sslmode=disable connect_timeout=10",
+		ListenAddr:      ":80",
+		ReadTimeout:     duration{15 * time.Second},
+		WriteTimeout:    duration{15 * time.Second},
+		IdleTimeout:     duration{60 * time.Second},
+		ShutdownTimeout: duration{30 * time.Second},
+		CacheEntries:    1000,
+		CacheMaxAgeSecs: 300,
+		AccessLog:       true,
+
+		TurnoutSourcePriority: gDefaultTurnoutPriority,
+		TurnoutDisagreement:   gDefaultTurnoutDisagreement,
+	}
+}
+
+// setting is one config setting, which can be given by a flag or an env var.
+type setting struct {
+	name  string // flag name; the env var is EOH_API_ + name in caps
+	usage string
+	set   func(string) error
+}
+
+// gBoolSettings are the settings whose flags may be given without a value
+// (e.g., -access-log).
+var gBoolSettings = map[string]bool{"access-log": true}
+
+// flagValue holds a flag's value until the config has been read.
+type flagValue struct {
+	val    string
+	isBool bool
+}
+
+func (self *flagValue) String() string { return self.val }
+
+func (self *flagValue) Set(s string) error {
+	self.val = s
+	return nil
+}
+
+func (self *flagValue) IsBoolFlag() bool { return self.isBool }
+
+func intSetter(p *int) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*p = n
+		return nil
+	}
+}
+
+func stringSetter(p *string) func(string) error {
+	return func(s string) error {
+		*p = s
+		return nil
+	}
+}
+
+func boolSetter(p *bool) func(string) error {
+	return func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*p = b
+		return nil
+	}
+}
+
+func floatSetter(p *float64) func(string) error {
+	return func(s string) error {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*p = f
+		return nil
+	}
+}
+
+// listSetter sets a list from a comma-separated string.
+func listSetter(p *[]string) func(string) error {
+	return func(s string) error {
+		*p = nil
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				*p = append(*p, item)
+			}
+		}
+		return nil
+	}
+}
+
+func (self *config) settings() []setting {
+	return []setting{
+		{"dsn", "Postgres connection string", stringSetter(&self.DSN)},
+		{"sqlite", "Serve from the SQLite DB (built by map-data) at this path instead of Postgres",
+			stringSetter(&self.SQLitePath)},
+		{"max-open-conns", "Max open DB connections (0 = unlimited)", intSetter(&self.MaxOpenConns)},
+		{"max-idle-conns", "Max idle DB connections", intSetter(&self.MaxIdleConns)},
+		{"conn-max-lifetime", "Max lifetime of a DB connection (e.g., 5m)", self.ConnMaxLifetime.Set},
+		{"listen", "Address to listen on", stringSetter(&self.ListenAddr)},
+		{"read-timeout", "HTTP read timeout", self.ReadTimeout.Set},
+		{"write-timeout", "HTTP write timeout", self.WriteTimeout.Set},
+		{"idle-timeout", "HTTP keep-alive timeout", self.IdleTimeout.Set},
+		{"shutdown-timeout", "How long to wait for requests to finish when shutting down",
+			self.ShutdownTimeout.Set},
+		{"tls-cert", "TLS certificate file (enables HTTPS)", stringSetter(&self.TLSCertFile)},
+		{"tls-key", "TLS key file", stringSetter(&self.TLSKeyFile)},
+		{"cache-entries", "Max responses to cache in memory (0 = don't cache)",
+			intSetter(&self.CacheEntries)},
+		{"cache-max-age", "Seconds that clients may cache responses without revalidating",
+			intSetter(&self.CacheMaxAgeSecs)},
+		{"turnout-sources", "Turnout sources in order of preference (comma-separated parts of their names)",
+			listSetter(&self.TurnoutSourcePriority)},
+		{"turnout-disagreement", "Flag turnouts whose sources differ by more than this fraction",
+			floatSetter(&self.TurnoutDisagreement)},
+		{"max-data-age", "Report not ready if the data was loaded longer ago than this (e.g., 720h; 0 = no limit)",
+			self.MaxDataAge.Set},
+		{"access-log", "Write an access log (as JSON lines) to stdout", boolSetter(&self.AccessLog)},
+		{"pprof-listen", "Address (e.g., localhost:6060) on which to serve pprof (off by default)",
+			stringSetter(&self.PprofListenAddr)},
+		{"app-dir", "Serve the app's build from this directory (e.g., ../../../app/build)",
+			stringSetter(&self.AppDir)},
+		{"export-static", "Write every JSON response to this directory (for static hosting), then exit",
+			stringSetter(&self.StaticExportDir)},
+	}
+}
+
+func envVarName(settingName string) string {
+	return "EOH_API_" + strings.ToUpper(strings.Replace(settingName, "-", "_", -1))
+}
+
+// loadConfig reads the config from the config file, env vars, and the
+// given command-line args.
+func loadConfig(args []string) (*config, error) {
+	cfg := defaultConfig()
+
+	// parse flags into a separate set of values, so that they can be
+	// applied last
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("EOH_API_CONFIG"), "JSON config file")
+	flagVals := make(map[string]*flagValue)
+	for _, s := range cfg.settings() {
+		flagVals[s.name] = &flagValue{isBool: gBoolSettings[s.name]}
+		fs.Var(flagVals[s.name], s.name,
+			fmt.Sprintf("%v (env: %v)", s.usage, envVarName(s.name)))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// read config file
+	if len(*configPath) > 0 {
+		f, err := os.Open(*configPath)
+		if err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(f)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&cfg)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", *configPath, err)
+		}
+	}
+
+	// apply env vars, then flags
+	for _, s := range cfg.settings() {
+		if val, ok := os.LookupEnv(envVarName(s.name)); ok {
+			if err := s.set(val); err != nil {
+				return nil, fmt.Errorf("%v: %v", envVarName(s.name), err)
+			}
+		}
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" {
+			return
+		}
+		for _, s := range cfg.settings() {
+			if s.name == f.Name {
+				if err = s.set(flagVals[s.name].val); err != nil {
+					err = fmt.Errorf("-%v: %v", s.name, err)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// check
+	if (len(cfg.TLSCertFile) > 0) != (len(cfg.TLSKeyFile) > 0) {
+		return nil, errors.New("TLS needs both a certificate and a key")
+	}
+	if cfg.TurnoutDisagreement < 0 {
+		return nil, errors.New("turnout-disagreement must not be negative")
+	}
+	return &cfg, nil
+}
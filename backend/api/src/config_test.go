@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestLoadConfigFlags(t *testing.T) {
+	cases := []struct {
+		args          []string
+		wantAccessLog bool
+		wantListen    string
+	}{
+		{[]string{"-access-log"}, true, ""},
+		{[]string{"-access-log", "-listen", ":9000"}, true, ":9000"},
+		{[]string{"-access-log=false"}, false, ""},
+		{[]string{"-listen", ":9000"}, true, ":9000"}, /* the default */
+	}
+	for _, c := range cases {
+		cfg, err := loadConfig(c.args)
+		if err != nil {
+			t.Errorf("%v: %v", c.args, err)
+			continue
+		}
+		if cfg.AccessLog != c.wantAccessLog {
+			t.Errorf("%v: got AccessLog %v; want %v", c.args, cfg.AccessLog, c.wantAccessLog)
+		}
+		if len(c.wantListen) > 0 && cfg.ListenAddr != c.wantListen {
+			t.Errorf("%v: got ListenAddr %q; want %q", c.args, cfg.ListenAddr, c.wantListen)
+		}
+	}
+}
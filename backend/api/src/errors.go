@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+)
+
+/*
+Errors are sent to clients as JSON, like this:
+
+	{"error": {"code": "not_found", "message": "No such congress: 200",
+	  "requestId": "3f2a9c01d4e5b678"}}
+
+Handlers return errors instead of writing them, and apiHandler turns them into
+responses.  An *apiError is sent as is; any other error is an internal error,
+which is logged (with the request ID) but not shown to the client.
+*/
+
+// error codes
+const (
+	gErrBadRequest       = "bad_request"
+	gErrInvalidParams    = "invalid_parameters"
+	gErrNotFound         = "not_found"
+	gErrMethodNotAllowed = "method_not_allowed"
+	gErrNotAcceptable    = "not_acceptable"
+	gErrInternal         = "internal_error"
+)
+
+const gRequestIDHeader = "X-Request-ID"
+
+// gClientRequestIDPattern matches the request IDs that we accept from
+// clients.
+var gClientRequestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// paramError says what is wrong with a route var or query param.
+type paramError struct {
+	Param   string `json:"param"`
+	Problem string `json:"problem"`
+}
+
+type apiError struct {
+	status  int
+	code    string
+	message string
+	details []paramError
+}
+
+func (self *apiError) Error() string {
+	return self.message
+}
+
+func errNotFound(format string, args ...interface{}) *apiError {
+	return &apiError{
+		status:  http.StatusNotFound,
+		code:    gErrNotFound,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+func errBadRequest(format string, args ...interface{}) *apiError {
+	return &apiError{
+		status:  http.StatusBadRequest,
+		code:    gErrBadRequest,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+func errInvalidParams(details []paramError) *apiError {
+	return &apiError{
+		status:  http.StatusBadRequest,
+		code:    gErrInvalidParams,
+		message: "Invalid parameters",
+		details: details,
+	}
+}
+
+func errNotAcceptable(format string, args ...interface{}) *apiError {
+	return &apiError{
+		status:  http.StatusNotAcceptable,
+		code:    gErrNotAcceptable,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+type errorInfo struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"requestId"`
+	Details   []paramError `json:"details,omitempty"`
+}
+
+type errorResponse struct {
+	Error errorInfo `json:"error"`
+}
+
+// writeError sends the given error to the client.
+func writeError(resp http.ResponseWriter, req *http.Request, err error) {
+	reqID := requestID(req.Context())
+	apiErr, ok := err.(*apiError)
+	if !ok {
+		log.Printf("[%v] %v", reqID, err)
+		apiErr = &apiError{
+			status:  http.StatusInternalServerError,
+			code:    gErrInternal,
+			message: "Internal error",
+		}
+	}
+
+	resp.Header().Set(gContentTypeHeader, gJSONContentType)
+	resp.WriteHeader(apiErr.status)
+	json.NewEncoder(resp).Encode(&errorResponse{Error: errorInfo{
+		Code:      apiErr.code,
+		Message:   apiErr.message,
+		RequestID: reqID,
+		Details:   apiErr.details,
+	}})
+}
+
+// writeJSON sends the given value as JSON.
+func writeJSON(resp http.ResponseWriter, value interface{}) error {
+	return writeJSONWithStatus(resp, http.StatusOK, value)
+}
+
+// writeJSONWithStatus sends the given value as JSON with the given status.
+func writeJSONWithStatus(resp http.ResponseWriter, statusCode int, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	resp.Header().Set(gContentTypeHeader, gJSONContentType)
+	resp.WriteHeader(statusCode)
+	resp.Write(append(data, '\n'))
+	return nil
+}
+
+// apiHandler is a handler that returns its errors instead of writing them.
+type apiHandler func(resp http.ResponseWriter, req *http.Request) error
+
+func (self apiHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if err := self(resp, req); err != nil {
+		writeError(resp, req, err)
+	}
+}
+
+var gNotFoundHandler = apiHandler(func(resp http.ResponseWriter, req *http.Request) error {
+	return errNotFound("No such resource: %v", req.URL.Path)
+})
+
+var gMethodNotAllowedHandler = apiHandler(func(resp http.ResponseWriter, req *http.Request) error {
+	return &apiError{
+		status:  http.StatusMethodNotAllowed,
+		code:    gErrMethodNotAllowed,
+		message: fmt.Sprintf("Method not allowed: %v", req.Method),
+	}
+})
+
+type requestIDKey struct{}
+
+// requestID returns the ID of the request with the given context.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// withRequestID gives each request an ID, which is sent back in the
+// X-Request-ID header and in errors.  If the client (or a proxy) sent an ID,
+// we use it.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(gRequestIDHeader)
+		if !gClientRequestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		resp.Header().Set(gRequestIDHeader, id)
+		ctx := context.WithValue(req.Context(), requestIDKey{}, id)
+		next.ServeHTTP(resp, req.WithContext(ctx))
+	})
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/api/store"
+	"github.com/gorilla/mux"
+)
+
+/*
+The congress, state, and district routes can also return CSV or GeoJSON.
+Clients ask for them with a suffix on the path (e.g., ".../states.csv") or
+with the Accept header.  Exports are streamed: we write each district as we
+read its facts (or, for GeoJSON, its shapes) from the DB.
+*/
+
+const (
+	gFormatJSON    = "json"
+	gFormatCSV     = "csv"
+	gFormatGeoJSON = "geojson"
+)
+
+const gCSVContentType = "text/csv; charset=utf-8"
+const gGeoJSONContentType = "application/geo+json"
+
+// gFormatSuffixPattern is the pattern for the "format" route var.
+const gFormatSuffixPattern = `{format:(?:\.csv|\.geojson)?}`
+
+// requestedFormat returns the format that the client wants.
+func requestedFormat(req *http.Request) string {
+	switch mux.Vars(req)["format"] {
+	case ".csv":
+		return gFormatCSV
+	case ".geojson":
+		return gFormatGeoJSON
+	}
+
+	for _, part := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		switch mediaType {
+		case "application/json":
+			return gFormatJSON
+		case "text/csv":
+			return gFormatCSV
+		case "application/geo+json", "application/vnd.geo+json":
+			return gFormatGeoJSON
+		}
+	}
+	return gFormatJSON
+}
+
+// districtRows are what an export has read about a district.
+type districtRows struct {
+	facts  []store.Fact
+	shapes []json.RawMessage
+}
+
+// districtExporter writes districts and their facts in some format.
+type districtExporter interface {
+	begin() error
+
+	// writeDistrict writes a district with its facts and shapes (either of
+	// which may be empty).
+	writeDistrict(d store.District, rows districtRows) error
+
+	// writeIrregularState writes a state whose districts we don't report
+	// because they're irregular (see store.Irregularities).
+	writeIrregularState(state string, irregularHow []string) error
+
+	end() error
+}
+
+func districtLess(a, b store.District) bool {
+	if a.State != b.State {
+		return a.State < b.State
+	}
+	return a.Nbr < b.Nbr
+}
+
+// forEachDistrictFacts calls f with each district in the given scope that
+// has facts, in order of state and district, and with its facts.
+func forEachDistrictFacts(ctx context.Context, scope store.Scope,
+	f func(d store.District, facts []store.Fact) error) error {
+
+	var pending []store.Fact /* facts about the same district */
+	err := gStore.ForEachFact(ctx, scope, func(fact store.Fact) error {
+		if len(pending) > 0 && pending[0].District != fact.District {
+			if err := f(pending[0].District, pending); err != nil {
+				return err
+			}
+			pending = pending[:0]
+		}
+		pending = append(pending, fact)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return f(pending[0].District, pending)
+	}
+	return nil
+}
+
+// forEachDistrictShapes calls f with each district in the given scope that
+// has shapes, in order of state and district, and with its shapes.
+func forEachDistrictShapes(ctx context.Context, scope store.Scope,
+	f func(d store.District, shapes []json.RawMessage) error) error {
+
+	var pending []json.RawMessage /* shapes of pendingDistrict */
+	var pendingDistrict store.District
+	err := gStore.ForEachDistrictShape(ctx, scope, func(shape store.DistrictShape) error {
+		if len(pending) > 0 && pendingDistrict != shape.District {
+			if err := f(pendingDistrict, pending); err != nil {
+				return err
+			}
+			pending = pending[:0]
+		}
+		pendingDistrict = shape.District
+		pending = append(pending, shape.Geometry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return f(pendingDistrict, pending)
+	}
+	return nil
+}
+
+// exportDistricts writes the districts in the given scope, with their facts
+// (and, if withShapes, their shapes), to the given exporter.
+func exportDistricts(ctx context.Context, exporter districtExporter,
+	scope store.Scope, withShapes bool) error {
+
+	// get districts
+	allDistricts, err := gStore.Districts(ctx, scope.Congress)
+	if err != nil {
+		return err
+	}
+	var districts []store.District
+	for _, d := range allDistricts {
+		if len(scope.State) > 0 && d.State != scope.State {
+			continue
+		}
+		if scope.District != nil && d.Nbr != *scope.District {
+			continue
+		}
+		districts = append(districts, d)
+	}
+
+	// get irregularities
+	irregularities, err := gStore.Irregularities(ctx, scope.Congress)
+	if err != nil {
+		return err
+	}
+
+	/*
+		With shapes, we stream the shapes (which are big) and look up each
+		district's facts (which are small) in a map that we read first.  Only
+		one query is open at a time, so an export can't deadlock when the pool
+		of DB connections is small.
+	*/
+	var factsByDistrict map[store.District][]store.Fact
+	if withShapes {
+		facts, err := store.Facts(ctx, gStore, scope)
+		if err != nil {
+			return err
+		}
+		factsByDistrict = make(map[store.District][]store.Fact)
+		for _, f := range facts {
+			factsByDistrict[f.District] = append(factsByDistrict[f.District], f)
+		}
+	}
+
+	if err = exporter.begin(); err != nil {
+		return err
+	}
+
+	lastIrregularState := ""
+	writeDistrict := func(d store.District, rows districtRows) error {
+		irregularHow := irregularities[d.State]
+		if len(irregularHow) == 0 {
+			return exporter.writeDistrict(d, rows)
+		}
+		if d.State == lastIrregularState {
+			return nil
+		}
+		lastIrregularState = d.State
+		return exporter.writeIrregularState(d.State, irregularHow)
+	}
+
+	/*
+		Both the districts and the streamed rows are in order of state and
+		district, so we merge them.  writeUpTo writes the districts before d
+		(which have no streamed rows) and then d with the given rows.  If d is
+		nil, it writes the remaining districts.
+	*/
+	next := 0
+	writeUpTo := func(d *store.District, rows districtRows) error {
+		for next < len(districts) && (d == nil || districtLess(districts[next], *d)) {
+			skipped := districts[next]
+			err := writeDistrict(skipped, districtRows{facts: factsByDistrict[skipped]})
+			if err != nil {
+				return err
+			}
+			next++
+		}
+		if d == nil {
+			return nil
+		}
+		if next < len(districts) && districts[next] == *d {
+			next++
+		}
+		return writeDistrict(*d, rows)
+	}
+
+	if withShapes {
+		err = forEachDistrictShapes(ctx, scope,
+			func(d store.District, shapes []json.RawMessage) error {
+				return writeUpTo(&d, districtRows{facts: factsByDistrict[d], shapes: shapes})
+			})
+	} else {
+		err = forEachDistrictFacts(ctx, scope, func(d store.District, facts []store.Fact) error {
+			return writeUpTo(&d, districtRows{facts: facts})
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if err = writeUpTo(nil, districtRows{}); err != nil {
+		return err
+	}
+	return exporter.end()
+}
+
+// csvExporter writes one row per fact.  Districts without facts get one row
+// with empty fact columns, and irregular states get one row with an empty
+// district.
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) *csvExporter {
+	return &csvExporter{writer: csv.NewWriter(w)}
+}
+
+func (self *csvExporter) begin() error {
+	return self.writer.Write([]string{"state", "district", "irregular_how", "fact",
+		"value", "margin_of_error", "source"})
+}
+
+func (self *csvExporter) writeDistrict(d store.District, rows districtRows) error {
+	district := strconv.Itoa(d.Nbr)
+	facts := rows.facts
+	if len(facts) == 0 {
+		return self.writer.Write([]string{d.State, district, "", "", "", "", ""})
+	}
+	for _, f := range facts {
+		moe := ""
+		if f.MarginOfError != nil {
+			moe = strconv.Itoa(*f.MarginOfError)
+		}
+		err := self.writer.Write([]string{d.State, district, "", f.Type,
+			strconv.Itoa(f.Value), moe, f.Source})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *csvExporter) writeIrregularState(state string, irregularHow []string) error {
+	return self.writer.Write([]string{state, "", strings.Join(irregularHow, ";"),
+		"", "", "", ""})
+}
+
+func (self *csvExporter) end() error {
+	self.writer.Flush()
+	return self.writer.Error()
+}
+
+// shapesGeometry returns a geometry made of the given shapes, or nil if there
+// are none.
+func shapesGeometry(shapes []json.RawMessage) (json.RawMessage, error) {
+	switch len(shapes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return shapes[0], nil
+	default:
+		return json.Marshal(map[string]interface{}{
+			"type":       "GeometryCollection",
+			"geometries": shapes,
+		})
+	}
+}
+
+/*
+geoJSONExporter writes a FeatureCollection with one feature per district,
+whose properties hold the district's facts (as in the JSON responses).
+Irregular states get one feature (without a geometry) with an irregularHow
+property.
+*/
+type geoJSONExporter struct {
+	writer   io.Writer
+	nbrSoFar int
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   interface{}            `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+func newGeoJSONExporter(w io.Writer) *geoJSONExporter {
+	return &geoJSONExporter{writer: w}
+}
+
+func (self *geoJSONExporter) begin() error {
+	_, err := io.WriteString(self.writer, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (self *geoJSONExporter) writeFeature(geometry json.RawMessage,
+	props map[string]interface{}) error {
+
+	data, err := json.Marshal(&geoJSONFeature{
+		Type:       "Feature",
+		Geometry:   geometry,
+		Properties: props,
+	})
+	if err != nil {
+		return err
+	}
+	if self.nbrSoFar > 0 {
+		if _, err = io.WriteString(self.writer, ",\n"); err != nil {
+			return err
+		}
+	}
+	self.nbrSoFar++
+	_, err = self.writer.Write(data)
+	return err
+}
+
+func (self *geoJSONExporter) writeDistrict(d store.District, rows districtRows) error {
+	distFacts := make(districtFacts)
+	for _, f := range rows.facts {
+		addFact(distFacts, f)
+	}
+	geometry, err := shapesGeometry(rows.shapes)
+	if err != nil {
+		return err
+	}
+	return self.writeFeature(geometry, map[string]interface{}{
+		"state":    d.State,
+		"district": d.Nbr,
+		"facts":    distFacts,
+	})
+}
+
+func (self *geoJSONExporter) writeIrregularState(state string, irregularHow []string) error {
+	return self.writeFeature(nil, map[string]interface{}{
+		"state":        state,
+		"irregularHow": irregularHow,
+	})
+}
+
+func (self *geoJSONExporter) end() error {
+	_, err := io.WriteString(self.writer, "]}\n")
+	return err
+}
+
+// startedWriter is a writer that remembers whether anything was written to
+// it.
+type startedWriter struct {
+	writer  io.Writer
+	started bool
+}
+
+func (self *startedWriter) Write(data []byte) (int, error) {
+	self.started = true
+	return self.writer.Write(data)
+}
+
+// writeDistrictsExport streams the districts in the given scope in the given
+// format (CSV or GeoJSON).  It returns an error only if it failed before
+// writing anything; later errors (after the status has been sent) are just
+// logged.
+func writeDistrictsExport(resp http.ResponseWriter, req *http.Request, format string,
+	scope store.Scope) error {
+
+	var exporter districtExporter
+	writer := &startedWriter{writer: resp}
+	if format == gFormatCSV {
+		resp.Header().Set(gContentTypeHeader, gCSVContentType)
+		exporter = newCSVExporter(writer)
+	} else {
+		resp.Header().Set(gContentTypeHeader, gGeoJSONContentType)
+		exporter = newGeoJSONExporter(writer)
+	}
+	err := exportDistricts(req.Context(), exporter, scope, format == gFormatGeoJSON)
+	if err != nil && writer.started {
+		log.Printf("Export failed: %v", err)
+		return nil
+	}
+	return err
+}
+
+// writeCongressesCSV writes the given congresses as CSV.
+func writeCongressesCSV(w io.Writer, congresses []store.Congress) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"congress", "start_year"})
+	for _, con := range congresses {
+		writer.Write([]string{fmt.Sprintf("%v", con.Nbr), fmt.Sprintf("%v", con.StartYear)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"expandourhouse.com/lib/apportionment"
+	"expandourhouse.com/lib/proposals"
+)
+
+/*
+A forecast runs the apportionment after a future census with each scenario
+of the projected populations (see loaddata's projections package), both for
+the House's current size and for the proposed rules (see package proposals).
+
+The current seats are those of the last actual apportionment, recomputed
+from that census's populations.  A state's seats are at risk if it loses
+seats in some scenario, if its seats depend on the scenario, or if it holds
+one of the last few seats in some scenario.
+*/
+
+// gScenarioOrder gives the order in which we list the scenarios.
+var gScenarioOrder = map[string]int{"low": 0, "middle": 1, "high": 2}
+
+// gCurrentHouse is the name of the House of the current size in forecasts.
+const gCurrentHouse = "current"
+
+type forecastState struct {
+	Population   int  `json:"population"`
+	Seats        int  `json:"seats"`
+	CurrentSeats *int `json:"currentSeats"` /* nil == unknown */
+	Change       *int `json:"change"`
+}
+
+type forecastScenario struct {
+	PopulationSource string                    `json:"populationSource"`
+	Size             int                       `json:"size"`
+	States           map[string]*forecastState `json:"states"`
+	LastIn           []*prioritySeat           `json:"lastIn"`
+	FirstOut         []*prioritySeat           `json:"firstOut"`
+}
+
+type seatsAtRisk struct {
+	State        string         `json:"state"`
+	CurrentSeats *int           `json:"currentSeats"`
+	Seats        map[string]int `json:"seats"`  /* by scenario */
+	LastIn       []string       `json:"lastIn"` /* scenarios */
+}
+
+type forecastHouse struct {
+	Description string                       `json:"description"`
+	Scenarios   map[string]*forecastScenario `json:"scenarios"`
+	AtRisk      []*seatsAtRisk               `json:"atRisk"`
+}
+
+type forecast struct {
+	Year          int                       `json:"year"`
+	FirstCongress int                       `json:"firstCongress"`
+	BaseCensus    *int                      `json:"baseCensus"` /* nil == no census populations */
+	CurrentSize   int                       `json:"currentSize"`
+	Scenarios     []string                  `json:"scenarios"`
+	Houses        map[string]*forecastHouse `json:"houses"`
+}
+
+type projectedPops struct {
+	values  map[string]int
+	sources map[string]bool
+}
+
+// getCurrentSeats returns the size of the House and the states' seats
+// according to the last actual apportionment before the given year.  The
+// seats are nil if we don't have that census's populations.
+func getCurrentSeats(req *http.Request, year int) (*apportionment.HistoricalApportionment,
+	map[string]int, error) {
+
+	var base *apportionment.HistoricalApportionment
+	for _, h := range apportionment.HistoricalApportionments() {
+		if h.CensusYear < year {
+			h := h
+			base = &h
+		}
+	}
+	if base == nil {
+		return nil, nil, nil
+	}
+
+	pops, err := gStore.CensusStatePops(req.Context(), base.CensusYear)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pops) == 0 {
+		return base, nil, nil
+	}
+	popValues := make(map[string]int)
+	for state, pop := range pops {
+		popValues[state] = pop.Value
+	}
+	res, err := apportionment.Apportion(popValues, base.Size, base.Method)
+	if err != nil {
+		return nil, nil, err
+	}
+	return base, res.Seats, nil
+}
+
+// forecastScenarioFor applies the rule to a scenario's populations.
+func forecastScenarioFor(rule proposals.Rule, pops *projectedPops,
+	currentSeats map[string]int) (*forecastScenario, error) {
+
+	proposal, err := rule.Apply(pops.values)
+	if err != nil {
+		return nil, err
+	}
+	result := forecastScenario{
+		PopulationSource: joinSources(pops.sources),
+		Size:             proposal.Size,
+		States:           make(map[string]*forecastState),
+		LastIn:           []*prioritySeat{},
+		FirstOut:         []*prioritySeat{},
+	}
+	for state, seats := range proposal.Seats {
+		s := forecastState{Population: pops.values[state], Seats: seats}
+		if current, ok := currentSeats[state]; ok {
+			change := seats - current
+			s.CurrentSeats, s.Change = &current, &change
+		}
+		result.States[state] = &s
+	}
+
+	/* A fixed ratio has no order of seats, so nothing is near the cutoff */
+	if rule.Kind != proposals.Ratio && proposal.Size+gNbrNearCutoff <= gMaxHouseSize {
+		res, err := apportionment.Apportion(pops.values, proposal.Size+gNbrNearCutoff,
+			apportionment.HuntingtonHill)
+		if err != nil {
+			return nil, err
+		}
+		_, result.LastIn, result.FirstOut = makePrioritySeats(pops.values,
+			res.Assignments, proposal.Size)
+	}
+	return &result, nil
+}
+
+// findSeatsAtRisk returns the states whose seats are at risk in the given
+// scenarios, in order of state.
+func findSeatsAtRisk(scenarios map[string]*forecastScenario,
+	currentSeats map[string]int) []*seatsAtRisk {
+
+	byState := make(map[string]*seatsAtRisk)
+	for name, scenario := range scenarios {
+		for state, s := range scenario.States {
+			risk, ok := byState[state]
+			if !ok {
+				risk = &seatsAtRisk{State: state, Seats: make(map[string]int), LastIn: []string{}}
+				if current, ok := currentSeats[state]; ok {
+					risk.CurrentSeats = &current
+				}
+				byState[state] = risk
+			}
+			risk.Seats[name] = s.Seats
+		}
+		for _, seat := range scenario.LastIn {
+			risk := byState[seat.State]
+			risk.LastIn = append(risk.LastIn, name)
+		}
+	}
+
+	result := []*seatsAtRisk{}
+	for _, risk := range byState {
+		sort.Strings(risk.LastIn)
+		atRisk := len(risk.LastIn) > 0
+		min, max := -1, -1
+		for _, seats := range risk.Seats {
+			if min < 0 || seats < min {
+				min = seats
+			}
+			if seats > max {
+				max = seats
+			}
+		}
+		if min != max || (risk.CurrentSeats != nil && min < *risk.CurrentSeats) {
+			atRisk = true
+		}
+		if atRisk {
+			result = append(result, risk)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].State < result[j].State })
+	return result
+}
+
+func handleGetForecast(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	year := p.routeInt("year")
+	historical := apportionment.HistoricalApportionments()
+	lastCensus := historical[len(historical)-1].CensusYear
+	if year%10 != 0 || year <= lastCensus {
+		p.addProblem("year", "Must be a census year after %v", lastCensus)
+	}
+	rules := parseRules(p, "rules")
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	// get projections
+	projections, err := gStore.StatePopProjections(req.Context())
+	if err != nil {
+		return err
+	}
+	scenarioPops := make(map[string]*projectedPops)
+	var scenarios []string
+	for _, proj := range projections {
+		if proj.Year != year {
+			continue
+		}
+		pops, ok := scenarioPops[proj.Scenario]
+		if !ok {
+			pops = &projectedPops{make(map[string]int), make(map[string]bool)}
+			scenarioPops[proj.Scenario] = pops
+			scenarios = append(scenarios, proj.Scenario)
+		}
+		pops.values[proj.State] = proj.Value
+		pops.sources[proj.Source] = true
+	}
+	if len(scenarioPops) == 0 {
+		return errNotFound("No population projections for %v", year)
+	}
+	sort.Slice(scenarios, func(i, j int) bool {
+		return gScenarioOrder[scenarios[i]] < gScenarioOrder[scenarios[j]]
+	})
+
+	// get current seats
+	base, currentSeats, err := getCurrentSeats(req, year)
+	if err != nil {
+		return err
+	}
+	result := forecast{
+		Year:          year,
+		FirstCongress: apportionment.FirstCongress(year),
+		CurrentSize:   gFixedSize,
+		Scenarios:     scenarios,
+		Houses:        make(map[string]*forecastHouse),
+	}
+	if base != nil {
+		result.CurrentSize = base.Size
+		if currentSeats != nil {
+			result.BaseCensus = &base.CensusYear
+		}
+	}
+
+	// apportion
+	houseRules := map[string]proposals.Rule{
+		gCurrentHouse: {Kind: proposals.FixedSize, Param: result.CurrentSize},
+	}
+	for _, rule := range rules {
+		houseRules[rule.String()] = rule
+	}
+	for name, rule := range houseRules {
+		house := forecastHouse{
+			Description: rule.Description(),
+			Scenarios:   make(map[string]*forecastScenario),
+		}
+		for _, scenario := range scenarios {
+			house.Scenarios[scenario], err = forecastScenarioFor(rule,
+				scenarioPops[scenario], currentSeats)
+			if err != nil {
+				return errBadRequest("%v scenario: %v", scenario, err)
+			}
+		}
+		house.AtRisk = findSeatsAtRisk(house.Scenarios, currentSeats)
+		result.Houses[name] = &house
+	}
+
+	// make response
+	return writeJSON(resp, result)
+}
@@ -3,6 +3,11 @@ module expandourhouse.com/api
 go 1.13
 
 require (
+	expandourhouse.com/lib v0.0.0
 	github.com/gorilla/mux v1.7.3
 	github.com/lib/pq v1.2.0
+	github.com/mattn/go-sqlite3 v2.0.3+incompatible
+	github.com/paulmach/orb v0.1.6
 )
+
+replace expandourhouse.com/lib => ../../../lib
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"expandourhouse.com/api/store"
+)
+
+/*
+/healthz says whether the process is up; /readyz says whether it can serve
+data.  The API isn't ready if it can't reach the DB, if the DB lacks tables
+or views that we need, if the DB has no congresses or the latest congress
+with districts has no facts, or if the data or any source's data is older
+than MaxDataAge (if set).
+*/
+
+const gReadinessTimeout = 5 * time.Second
+
+// errFoundFact stops the search for a fact.
+var errFoundFact = errors.New("Found a fact")
+
+type readinessCheck struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+type sourceLoadInfo struct {
+	Source   string     `json:"source"`
+	LoadedAt *time.Time `json:"loadedAt"` /* nil == unknown */
+}
+
+type readinessInfo struct {
+	Ready         bool              `json:"ready"`
+	Checks        []*readinessCheck `json:"checks"`
+	DataVersion   *int64            `json:"dataVersion,omitempty"`
+	DataUpdatedAt *time.Time        `json:"dataUpdatedAt,omitempty"`
+	Sources       []*sourceLoadInfo `json:"sources"`
+}
+
+func (self *readinessInfo) addCheck(name string, err error) bool {
+	check := readinessCheck{Name: name, OK: err == nil}
+	if err != nil {
+		check.Message = err.Error()
+	}
+	self.Checks = append(self.Checks, &check)
+	return check.OK
+}
+
+func checkReadiness(ctx context.Context, maxDataAge time.Duration) *readinessInfo {
+	result := readinessInfo{Checks: []*readinessCheck{}, Sources: []*sourceLoadInfo{}}
+
+	// check DB
+	health, err := gStore.Health(ctx)
+	if !result.addCheck("db", err) {
+		return &result
+	}
+	err = nil
+	if len(health.MissingRelations) > 0 {
+		err = fmt.Errorf("Missing tables or views: %v",
+			strings.Join(health.MissingRelations, ", "))
+	}
+	if !result.addCheck("schema", err) {
+		return &result
+	}
+	for _, load := range health.Sources {
+		info := sourceLoadInfo{Source: load.Source}
+		if !load.LoadedAt.IsZero() {
+			loadedAt := load.LoadedAt.UTC()
+			info.LoadedAt = &loadedAt
+		}
+		result.Sources = append(result.Sources, &info)
+	}
+
+	// check that there is data
+	cons, err := gStore.Congresses(ctx)
+	if err == nil && len(cons) == 0 {
+		err = errors.New("The DB has no congresses")
+	}
+	if !result.addCheck("data", err) {
+		return &result
+	}
+	congress, err := latestCongressWithDistricts(ctx, cons)
+	if !result.addCheck("districts", err) {
+		return &result
+	}
+	err = gStore.ForEachFact(ctx, store.Scope{Congress: congress}, func(store.Fact) error {
+		return errFoundFact
+	})
+	if err == nil {
+		err = fmt.Errorf("Congress %v has no facts", congress)
+	} else if err == errFoundFact {
+		err = nil
+	}
+	if !result.addCheck("facts", err) {
+		return &result
+	}
+
+	// check data's age
+	version, err := gStore.DataVersion(ctx)
+	if err == nil {
+		result.DataVersion = &version.Nbr
+		if !version.UpdatedAt.IsZero() {
+			updatedAt := version.UpdatedAt.UTC()
+			result.DataUpdatedAt = &updatedAt
+		}
+		if maxDataAge > 0 {
+			if version.UpdatedAt.IsZero() {
+				err = errors.New("The data's age is unknown")
+			} else if age := time.Since(version.UpdatedAt); age > maxDataAge {
+				err = fmt.Errorf("The data is %v old (max is %v)",
+					age.Round(time.Second), maxDataAge)
+			}
+		}
+	}
+	if err == nil && maxDataAge > 0 {
+		err = checkSourcesAge(result.Sources, maxDataAge)
+	}
+	if !result.addCheck("freshness", err) {
+		return &result
+	}
+
+	result.Ready = true
+	return &result
+}
+
+// latestCongressWithDistricts returns the latest of the given congresses
+// that has districts.
+func latestCongressWithDistricts(ctx context.Context, cons []store.Congress) (int, error) {
+	for i := len(cons) - 1; i >= 0; i-- {
+		districts, err := gStore.Districts(ctx, cons[i].Nbr)
+		if err != nil {
+			return 0, err
+		}
+		if len(districts) > 0 {
+			return cons[i].Nbr, nil
+		}
+	}
+	return 0, errors.New("No congress has districts")
+}
+
+// checkSourcesAge returns an error if any of the given sources was last
+// loaded longer than maxDataAge ago, or at an unknown time.
+func checkSourcesAge(sources []*sourceLoadInfo, maxDataAge time.Duration) error {
+	var stale []string
+	for _, source := range sources {
+		if source.LoadedAt == nil || time.Since(*source.LoadedAt) > maxDataAge {
+			stale = append(stale, source.Source)
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("Sources not loaded in the last %v: %v", maxDataAge,
+			strings.Join(stale, "; "))
+	}
+	return nil
+}
+
+func handleHealthz(resp http.ResponseWriter, req *http.Request) error {
+	return writeJSON(resp, map[string]string{"status": "ok"})
+}
+
+func newReadyzHandler(maxDataAge time.Duration) apiHandler {
+	return func(resp http.ResponseWriter, req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), gReadinessTimeout)
+		defer cancel()
+		result := checkReadiness(ctx, maxDataAge)
+
+		// make response
+		resp.Header().Set("Cache-Control", "no-store")
+		if !result.Ready {
+			return writeJSONWithStatus(resp, http.StatusServiceUnavailable, result)
+		}
+		return writeJSON(resp, result)
+	}
+}
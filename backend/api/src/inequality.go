@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"expandourhouse.com/lib/inequality"
+)
+
+/*
+The inequality of representation in a congress, measured over the regular
+districts (as in the stats), both by turnout and by each type of
+population.
+*/
+
+type inequalityIndices struct {
+	NbrDistricts   int     `json:"nbrDistricts"`
+	MaxMinRatio    float64 `json:"maxMinRatio"`
+	Gini           float64 `json:"gini"`
+	LoosemoreHanby float64 `json:"loosemoreHanby"`
+	MajorityShare  float64 `json:"majorityShare"`
+}
+
+type congressInequality struct {
+	Turnout    *inequalityIndices `json:"turnout"` /* nil == unknown */
+	Population *inequalityIndices `json:"population"`
+	Adults     *inequalityIndices `json:"adults"`
+	Citizens   *inequalityIndices `json:"citizens"`
+	CVAP       *inequalityIndices `json:"cvap"`
+}
+
+// computeIndices returns the inequality indices for districts with the
+// given sizes, or nil if there are none.  Districts of size 0 (e.g., with no
+// votes counted because the race was uncontested) are left out.
+func computeIndices(sizes []int) (*inequalityIndices, error) {
+	var positive []int
+	for _, size := range sizes {
+		if size > 0 {
+			positive = append(positive, size)
+		}
+	}
+	if len(positive) == 0 {
+		return nil, nil
+	}
+	indices, err := inequality.Compute(positive)
+	if err != nil {
+		return nil, err
+	}
+	return &inequalityIndices{
+		NbrDistricts:   indices.NbrDistricts,
+		MaxMinRatio:    indices.MaxMinRatio,
+		Gini:           indices.Gini,
+		LoosemoreHanby: indices.LoosemoreHanby,
+		MajorityShare:  indices.MajorityShare,
+	}, nil
+}
+
+func getCongressInequality(ctx context.Context, congress int) (*congressInequality, error) {
+	values, err := getValuesPerRegDistrict(ctx, congress,
+		"turnout", "all", "adults", "citizens", "cvap")
+	if err != nil {
+		return nil, err
+	}
+
+	var result congressInequality
+	for _, target := range []struct {
+		factType string
+		indices  **inequalityIndices
+	}{
+		{"turnout", &result.Turnout},
+		{"all", &result.Population},
+		{"adults", &result.Adults},
+		{"citizens", &result.Citizens},
+		{"cvap", &result.CVAP},
+	} {
+		if *target.indices, err = computeIndices(values[target.factType]); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+func handleGetInequality(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireCongress(req.Context(), congress); err != nil {
+		return err
+	}
+
+	// compute indices
+	result, err := getCongressInequality(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+
+	// make response
+	return writeJSON(resp, result)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"expandourhouse.com/api/store"
+)
+
+/*
+A state is irregular in a congress if its representatives' terms don't tell
+us how its people were divided among its seats.  Irregular states are left
+out of the statistics.  Here we explain why each irregular state was left
+out, with the terms that made it irregular.
+*/
+
+var gIrregularityExplanations = map[string]string{
+	"state_with_atlarge_and_nonatlarge_districts": "Some of the state's " +
+		"representatives were elected at large while others represented " +
+		"numbered districts, so each voter had more than one representative.",
+	"state_with_overlapping_terms": "Two or more representatives served the " +
+		"same district at the same time, so we can't tell how many people each " +
+		"one represented.",
+	"state_with_unknown_district": "We don't know which district some of the " +
+		"state's representatives served.",
+}
+
+type overlappingTerms struct {
+	District int                 `json:"district"`
+	First    *representativeInfo `json:"first"`
+	Second   *representativeInfo `json:"second"`
+}
+
+type irregularityInfo struct {
+	Type        string `json:"type"`
+	Explanation string `json:"explanation"`
+
+	// evidence (depending on the type)
+	OverlappingTerms []*overlappingTerms   `json:"overlappingTerms,omitempty"`
+	UnknownTerms     []*representativeInfo `json:"unknownDistrictTerms,omitempty"`
+	AtLargeTerms     []*representativeInfo `json:"atLargeTerms,omitempty"`
+	NumberedTerms    []*representativeInfo `json:"numberedDistrictTerms,omitempty"`
+}
+
+type stateIrregularities struct {
+	Irregular      bool                `json:"irregular"`
+	Irregularities []*irregularityInfo `json:"irregularities"`
+}
+
+// findOverlappingTerms returns the pairs of terms for the same district that
+// overlap in time.
+func findOverlappingTerms(terms []store.RepTerm) []*overlappingTerms {
+	// group by district
+	byDistrict := make(map[int][]store.RepTerm)
+	var districts []int
+	for _, term := range terms {
+		if term.District == nil {
+			continue
+		}
+		if _, ok := byDistrict[*term.District]; !ok {
+			districts = append(districts, *term.District)
+		}
+		byDistrict[*term.District] = append(byDistrict[*term.District], term)
+	}
+	sort.Ints(districts)
+
+	/* Like the state_with_overlapping_terms view */
+	var result []*overlappingTerms
+	for _, district := range districts {
+		distTerms := byDistrict[district]
+		sort.SliceStable(distTerms, func(i, j int) bool {
+			return distTerms[i].StartDate.Before(distTerms[j].StartDate)
+		})
+		for i, first := range distTerms {
+			for _, second := range distTerms[i+1:] {
+				if first.EndDate.After(second.StartDate) {
+					infos := makeRepresentativeInfos([]store.RepTerm{first, second})
+					result = append(result, &overlappingTerms{
+						District: district,
+						First:    infos[0],
+						Second:   infos[1],
+					})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// makeIrregularityInfo explains how the state with the given terms is
+// irregular in the given way.
+func makeIrregularityInfo(how string, terms []store.RepTerm) *irregularityInfo {
+	info := irregularityInfo{Type: how, Explanation: gIrregularityExplanations[how]}
+	switch how {
+	case "state_with_atlarge_and_nonatlarge_districts":
+		var atLarge, numbered []store.RepTerm
+		for _, term := range terms {
+			if term.District == nil {
+				continue
+			}
+			if *term.District == 0 {
+				atLarge = append(atLarge, term)
+			} else {
+				numbered = append(numbered, term)
+			}
+		}
+		info.AtLargeTerms = makeRepresentativeInfos(atLarge)
+		info.NumberedTerms = makeRepresentativeInfos(numbered)
+
+	case "state_with_overlapping_terms":
+		info.OverlappingTerms = findOverlappingTerms(terms)
+
+	case "state_with_unknown_district":
+		var unknown []store.RepTerm
+		for _, term := range terms {
+			if term.District == nil {
+				unknown = append(unknown, term)
+			}
+		}
+		info.UnknownTerms = makeRepresentativeInfos(unknown)
+	}
+	return &info
+}
+
+func handleGetStateIrregularities(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	state := p.routeString("state")
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireState(req.Context(), congress, state); err != nil {
+		return err
+	}
+
+	// get irregularities and terms
+	irregularities, err := gStore.Irregularities(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+	terms, err := gStore.RepTerms(req.Context(),
+		store.Scope{Congress: congress, State: state})
+	if err != nil {
+		return err
+	}
+
+	// explain irregularities
+	result := stateIrregularities{Irregularities: []*irregularityInfo{}}
+	for _, how := range irregularities[state] {
+		result.Irregularities = append(result.Irregularities,
+			makeIrregularityInfo(how, terms))
+	}
+	result.Irregular = len(result.Irregularities) > 0
+
+	// make response
+	return writeJSON(resp, &result)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"expandourhouse.com/api/spatial"
+	"expandourhouse.com/api/store"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+/*
+To find the district containing a point, we load all the district and state
+shapes of the congress into spatial indices.  Building the indices is slow,
+so we keep those of the most recently used congresses.
+*/
+
+const gMaxCachedLocators = 8
+
+// locator finds the districts and states of one congress that contain a
+// point.
+type locator struct {
+	dataVersion   int64
+	lastUsed      int64
+	districts     []store.DistrictShape /* without geometries */
+	districtIndex *spatial.Index
+	states        []string
+	stateIndex    *spatial.Index
+}
+
+type locatorCache struct {
+	mu       sync.Mutex
+	useCount int64
+	locators map[int]*locator
+}
+
+var gLocators = locatorCache{locators: make(map[int]*locator)}
+
+func parseGeometry(data []byte) (orb.Geometry, error) {
+	geom, err := geojson.UnmarshalGeometry(data)
+	if err != nil {
+		return nil, err
+	}
+	return geom.Geometry(), nil
+}
+
+func makeLocator(ctx context.Context, congress int) (*locator, error) {
+	var loc locator
+
+	// index district shapes
+	var geoms []orb.Geometry
+	err := gStore.ForEachDistrictShape(ctx, store.Scope{Congress: congress},
+		func(shape store.DistrictShape) error {
+			geom, err := parseGeometry(shape.Geometry)
+			if err != nil {
+				return err
+			}
+			geoms = append(geoms, geom)
+			shape.Geometry = nil
+			loc.districts = append(loc.districts, shape)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	loc.districtIndex = spatial.NewIndex(geoms)
+
+	// index state shapes
+	stateShapes, err := gStore.StateShapes(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	geoms = nil
+	for _, shape := range stateShapes {
+		geom, err := parseGeometry(shape.Geometry)
+		if err != nil {
+			return nil, err
+		}
+		geoms = append(geoms, geom)
+		loc.states = append(loc.states, shape.State)
+	}
+	loc.stateIndex = spatial.NewIndex(geoms)
+
+	return &loc, nil
+}
+
+// getLocator returns the locator for the given congress, making it if
+// necessary.
+func getLocator(ctx context.Context, congress int) (*locator, error) {
+	version, err := gStore.DataVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// check cache
+	gLocators.mu.Lock()
+	gLocators.useCount++
+	loc, ok := gLocators.locators[congress]
+	if ok && loc.dataVersion == version.Nbr {
+		loc.lastUsed = gLocators.useCount
+		gLocators.mu.Unlock()
+		return loc, nil
+	}
+	gLocators.mu.Unlock()
+
+	/*
+		Concurrent requests may make the same locator, but that is harmless.
+	*/
+	loc, err = makeLocator(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	loc.dataVersion = version.Nbr
+
+	// add to cache, evicting the least recently used locator
+	gLocators.mu.Lock()
+	defer gLocators.mu.Unlock()
+	gLocators.useCount++
+	loc.lastUsed = gLocators.useCount
+	gLocators.locators[congress] = loc
+	if len(gLocators.locators) > gMaxCachedLocators {
+		oldest := congress
+		for c, other := range gLocators.locators {
+			if other.lastUsed < gLocators.locators[oldest].lastUsed {
+				oldest = c
+			}
+		}
+		delete(gLocators.locators, oldest)
+	}
+	return loc, nil
+}
+
+type locationInfo struct {
+	State        string        `json:"state"`
+	District     *int          `json:"district"`
+	ShapeID      *string       `json:"shapeId"`
+	IrregularHow []string      `json:"irregularHow,omitempty"`
+	Facts        districtFacts `json:"facts,omitempty"`
+}
+
+func handleLocate(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	lat := p.requiredQueryFloat("lat", -90, 90)
+	lon := p.requiredQueryFloat("lon", -180, 180)
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireCongress(req.Context(), congress); err != nil {
+		return err
+	}
+	pt := orb.Point{lon, lat}
+
+	// find district and state
+	loc, err := getLocator(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+	var result locationInfo
+	if matches := loc.districtIndex.Containing(pt); len(matches) > 0 {
+		shape := loc.districts[matches[0]]
+		result.State = shape.District.State
+		result.District = &shape.District.Nbr
+		result.ShapeID = &shape.ShapeID
+	} else if matches := loc.stateIndex.Containing(pt); len(matches) > 0 {
+		result.State = loc.states[matches[0]]
+	} else {
+		return errNotFound("No district or state contains this point")
+	}
+
+	// get facts
+	irregularities, err := gStore.Irregularities(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+	result.IrregularHow = irregularities[result.State]
+	if result.District != nil && len(result.IrregularHow) == 0 {
+		d := store.District{State: result.State, Nbr: *result.District}
+		allFacts, err := getDistrictFacts(req.Context(), store.Scope{
+			Congress: congress,
+			State:    d.State,
+			District: &d.Nbr,
+		})
+		if err != nil {
+			return err
+		}
+		result.Facts = allFacts[d]
+		if result.Facts == nil {
+			result.Facts = make(districtFacts)
+		}
+	}
+
+	// make response
+	return writeJSON(resp, &result)
+}
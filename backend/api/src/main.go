@@ -1,19 +1,20 @@
 package main
 
 import (
-	"database/sql"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
-	"strconv"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"expandourhouse.com/api/store"
 	"github.com/gorilla/mux"
-	_ "github.com/lib/pq"
 )
 
-var gDb *sql.DB
+var gStore store.Store
 
 const gContentTypeHeader = "Content-Type"
 const gJSONContentType = "application/json"
@@ -22,35 +23,28 @@ type congressInfo struct {
 	StartYear int `json:"startYear"`
 }
 
-func handleGetCongresses(resp http.ResponseWriter, req *http.Request) {
-	congresses := make(map[string]*congressInfo)
-
+func handleGetCongresses(resp http.ResponseWriter, req *http.Request) error {
 	// get congresses from DB
-	rows, err := gDb.QueryContext(req.Context(),
-		"SELECT nbr, start_year FROM congress")
+	cons, err := gStore.Congresses(req.Context())
 	if err != nil {
-		goto done
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var nbr int
-		var con congressInfo
-		if err = rows.Scan(&nbr, &con.StartYear); err != nil {
-			goto done
-		}
-		congresses[fmt.Sprintf("%v", nbr)] = &con
+		return err
 	}
 
-done:
-	if err != nil {
-		resp.WriteHeader(http.StatusInternalServerError)
-		log.Printf("DB error: %v", err)
-		return
+	switch requestedFormat(req) {
+	case gFormatCSV:
+		resp.Header().Add(gContentTypeHeader, gCSVContentType)
+		return writeCongressesCSV(resp, cons)
+	case gFormatGeoJSON:
+		/* Congresses have no geometry */
+		return errNotAcceptable("Congresses are not available as GeoJSON")
 	}
 
 	// make response
-	resp.Header().Add(gContentTypeHeader, gJSONContentType)
-	json.NewEncoder(resp).Encode(congresses)
+	congresses := make(map[string]*congressInfo)
+	for _, con := range cons {
+		congresses[fmt.Sprintf("%v", con.Nbr)] = &congressInfo{StartYear: con.StartYear}
+	}
+	return writeJSON(resp, congresses)
 }
 
 type fact struct {
@@ -68,152 +62,243 @@ type districtFacts map[string]interface{}
 type stateInfo struct {
 	IrregularHow []string                 `json:"irregularHow"`
 	Districts    map[string]districtFacts `json:"districts"`
-}
+	Aggregates   *stateAggregates         `json:"aggregates"`
 
-func handleGetStates(resp http.ResponseWriter, req *http.Request) {
-	var err error
-	statusCode := http.StatusInternalServerError
-	var rows *sql.Rows
-	var states []string
-	var allDistricts map[string][]*districtInfo
-	result := make(map[string]*stateInfo)
+	/* Only in apportionments */
+	Apportionment *stateApportionment `json:"apportionment,omitempty"`
+}
 
+func handleGetStates(resp http.ResponseWriter, req *http.Request) error {
 	// get vars
-	vars := mux.Vars(req)
-	congress, err := strconv.Atoi(vars["congress"])
-	if err != nil {
-		statusCode = http.StatusBadRequest
-		goto done
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireCongress(req.Context(), congress); err != nil {
+		return err
 	}
 
-	// get all states for this congress
-	states, err = getStates(req.Context(), congress)
-	if err != nil {
-		goto done
+	// export?
+	if format := requestedFormat(req); format != gFormatJSON {
+		return writeDistrictsExport(resp, req, format, store.Scope{Congress: congress})
 	}
 
-	// get all districts for this congress
-	allDistricts, err = getDistricts(req.Context(), congress)
+	// get info for each state
+	result, err := getStates(req.Context(), congress)
 	if err != nil {
-		goto done
+		return err
 	}
 
-	// get info for each state
-	for _, stateAbbr := range states {
-		state := stateInfo{IrregularHow: nil, Districts: nil}
-		result[stateAbbr] = &state
-
-		// look for irregularities
-		state.IrregularHow, err = getStateIrregularities(req.Context(), stateAbbr, congress)
-		if err != nil {
-			goto done
-		}
-		if len(state.IrregularHow) > 0 {
-			continue
-		}
+	// make response
+	return writeJSON(resp, result)
+}
 
-		// get district info
-		state.Districts = make(map[string]districtFacts)
-		for _, di := range allDistricts[stateAbbr] {
-			distFacts, err := getDistrictFacts(req.Context(), di.rowID)
-			if err != nil {
-				goto done
-			}
-			state.Districts[fmt.Sprintf("%v", di.nbr)] = distFacts
-		}
+func handleGetState(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	state := p.routeString("state")
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireState(req.Context(), congress, state); err != nil {
+		return err
 	}
 
-done:
-	if rows != nil {
-		rows.Close()
+	// export?
+	if format := requestedFormat(req); format != gFormatJSON {
+		scope := store.Scope{Congress: congress, State: state}
+		return writeDistrictsExport(resp, req, format, scope)
 	}
+
+	// get info for the state
+	states, err := getStates(req.Context(), congress)
 	if err != nil {
-		resp.WriteHeader(statusCode)
-		log.Print(err)
-		return
+		return err
 	}
 
 	// make response
-	resp.Header().Add(gContentTypeHeader, gJSONContentType)
-	json.NewEncoder(resp).Encode(result)
+	return writeJSON(resp, states[state])
 }
 
-func handleGetDistrict(resp http.ResponseWriter, req *http.Request) {
-	var err error
-	statusCode := http.StatusInternalServerError
-	var rows *sql.Rows
-	var congress int
-	var state string
-	var district int
-	var districtID *int
-	var result districtFacts
-
+func handleGetDistrict(resp http.ResponseWriter, req *http.Request) error {
 	// get vars
-	vars := mux.Vars(req)
-	congress, err = strconv.Atoi(vars["congress"])
-	if err != nil {
-		statusCode = http.StatusBadRequest
-		goto done
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	district := store.District{
+		State: p.routeString("state"),
+		Nbr:   p.routeInt("district"),
 	}
-	state = vars["state"]
-	district, err = strconv.Atoi(vars["district"])
-	if err != nil {
-		statusCode = http.StatusBadRequest
-		goto done
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireDistrict(req.Context(), congress, district); err != nil {
+		return err
 	}
 
-	// get facts
-	districtID, err = getDistrictID(req.Context(), congress, state, district)
-	if err != nil {
-		goto done
+	// export?
+	scope := store.Scope{
+		Congress: congress,
+		State:    district.State,
+		District: &district.Nbr,
 	}
-	if districtID == nil {
-		statusCode = http.StatusNotFound
-		goto done
+	if format := requestedFormat(req); format != gFormatJSON {
+		return writeDistrictsExport(resp, req, format, scope)
 	}
-	result, err = getDistrictFacts(req.Context(), *districtID)
+
+	// get facts
+	allFacts, err := getDistrictFacts(req.Context(), scope)
 	if err != nil {
-		goto done
+		return err
 	}
-
-done:
-	if rows != nil {
-		rows.Close()
+	result := allFacts[district]
+	if result == nil {
+		result = make(districtFacts)
 	}
-	if err != nil {
-		resp.WriteHeader(statusCode)
-		if err != nil {
-			log.Print(err)
+
+	// make response
+	return writeJSON(resp, result)
+}
+
+func handleSignals(f func()) {
+	appSignal := make(chan os.Signal, 3)
+	signal.Notify(appSignal, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-appSignal:
+			log.Printf("Got signal: %v", sig)
+			f()
 		}
-		return
+	}()
+}
+
+func newRouter(cfg *config) http.Handler {
+	r := mux.NewRouter()
+	r.NotFoundHandler = gNotFoundHandler
+	r.MethodNotAllowedHandler = gMethodNotAllowedHandler
+	r.Handle("/metrics", gMetrics.Handler()).Methods("GET")
+	r.Handle("/healthz", apiHandler(handleHealthz)).Methods("GET")
+	r.Handle("/readyz", newReadyzHandler(cfg.MaxDataAge.Duration)).Methods("GET")
+
+	/* Only the API's responses are cached */
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(newResponseCache(cfg.CacheEntries, cfg.CacheMaxAgeSecs).middleware)
+	api.Handle("/congresses"+gFormatSuffixPattern,
+		apiHandler(handleGetCongresses)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/states"+gFormatSuffixPattern,
+		apiHandler(handleGetStates)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/states/{state:[^/.]+}"+gFormatSuffixPattern,
+		apiHandler(handleGetState)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/states/{state}/districts/{district:[0-9]+}"+
+		gFormatSuffixPattern, apiHandler(handleGetDistrict)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/states/{state}/irregularities",
+		apiHandler(handleGetStateIrregularities)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/states/{state}/representatives",
+		apiHandler(handleGetStateRepresentatives)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/states/{state}/districts/{district}/representatives",
+		apiHandler(handleGetDistrictRepresentatives)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/apportionment",
+		apiHandler(handleGetApportionment)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/stats",
+		apiHandler(handleGetCongressStats)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/inequality",
+		apiHandler(handleGetInequality)).Methods("GET", "HEAD")
+	api.Handle("/stats", apiHandler(handleGetAllStats)).Methods("GET", "HEAD")
+	api.Handle("/census/{year}/priority-list",
+		apiHandler(handleGetPriorityList)).Methods("GET", "HEAD")
+	api.Handle("/proposals", apiHandler(handleGetProposals)).Methods("GET", "HEAD")
+	api.Handle("/forecast/{year}", apiHandler(handleGetForecast)).Methods("GET", "HEAD")
+	api.Handle("/compare", apiHandler(handleCompare)).Methods("GET", "HEAD")
+	api.Handle("/congresses/{congress}/locate", apiHandler(handleLocate)).Methods("GET", "HEAD")
+
+	/* Everything else is the app's, if we're serving it */
+	if len(cfg.AppDir) > 0 {
+		r.NotFoundHandler = newAppHandler(cfg.AppDir)
 	}
 
-	// make response
-	resp.Header().Add(gContentTypeHeader, gJSONContentType)
-	json.NewEncoder(resp).Encode(result)
+	/* mux runs middleware only for matching routes, so we wrap the router */
+	return withRequestID(observeRequests(r, r, cfg.AccessLog))
 }
 
 func main() {
+	// get config
+	cfg, err := loadConfig(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// connect to DB
-	var err error
-	connStr := "host=db user=postgres password=pw dbname=house" +
-		" sslmode=disable connect_timeout=10"
-	gDb, err = sql.Open("postgres", connStr)
+	pool := store.PoolOptions{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime.Duration,
+	}
+	if len(cfg.SQLitePath) > 0 {
+		gStore, err = store.OpenSQLite(cfg.SQLitePath, pool)
+	} else {
+		gStore, err = store.OpenPostgres(cfg.DSN, pool)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer gDb.Close()
+	defer gStore.Close()
+	registerPoolMetrics(gStore)
+	gStore = &instrumentedStore{Store: gStore}
+	gTurnoutPolicy = turnoutPolicy{
+		Priority:     cfg.TurnoutSourcePriority,
+		Disagreement: cfg.TurnoutDisagreement,
+	}
+
+	if len(cfg.StaticExportDir) > 0 {
+		if err := exportStatic(context.Background(), cfg, cfg.StaticExportDir); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(cfg.PprofListenAddr) > 0 {
+		go servePprof(cfg.PprofListenAddr)
+	}
 
-	r := mux.NewRouter()
-	r.HandleFunc("/api/congresses", handleGetCongresses).Methods("GET")
-	r.HandleFunc("/api/congresses/{congress}/states", handleGetStates).Methods("GET")
-	r.HandleFunc("/api/congresses/{congress}/states/{state}/districts/{district}",
-		handleGetDistrict).Methods("GET")
 	srv := &http.Server{
-		Handler:      r,
-		Addr:         ":80",
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+		Handler:      newRouter(cfg),
+		Addr:         cfg.ListenAddr,
+		ReadTimeout:  cfg.ReadTimeout.Duration,
+		WriteTimeout: cfg.WriteTimeout.Duration,
+		IdleTimeout:  cfg.IdleTimeout.Duration,
+	}
+
+	/*
+		On SIGINT or SIGTERM, stop accepting connections and wait (up to
+		ShutdownTimeout) for in-flight requests to finish.  ListenAndServe
+		returns as soon as Shutdown is called, so we must wait for Shutdown
+		to return before exiting.
+	*/
+	shutDown := make(chan struct{})
+	handleSignals(func() {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			cfg.ShutdownTimeout.Duration)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Failed to shut down gracefully: %v", err)
+		}
+		close(shutDown)
+	})
+
+	// serve
+	log.Printf("Listening on %v", cfg.ListenAddr)
+	if len(cfg.TLSCertFile) > 0 {
+		err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
 	}
-	log.Fatal(srv.ListenAndServe())
+	<-shutDown
+	log.Printf("Shut down")
 }
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"expandourhouse.com/api/store"
+	"expandourhouse.com/lib/apiclient"
+)
+
+const gTestCongress = 115
+
+// newTestStore returns a memory store with a modern congress: 435 districts
+// among 50 states, each with turnout from two sources and the ACS's
+// populations.  The last state is irregular.
+func newTestStore() *store.Memory {
+	m := store.NewMemory()
+	m.AddCongress(store.Congress{Nbr: gTestCongress, StartYear: 2017})
+	nbrStates, nbrDistricts := 50, 435
+	for i := 0; i < nbrStates; i++ {
+		state := fmt.Sprintf("S%02d", i)
+		nbrSeats := nbrDistricts / nbrStates
+		if i < nbrDistricts%nbrStates {
+			nbrSeats++
+		}
+		for nbr := 1; nbr <= nbrSeats; nbr++ {
+			d := store.District{State: state, Nbr: nbr}
+			m.AddDistrict(gTestCongress, d)
+			m.AddFact(gTestCongress, store.Fact{District: d, Type: "turnout",
+				Value: 250000 + nbr, Source: "MIT Election Data"})
+			m.AddFact(gTestCongress, store.Fact{District: d, Type: "turnout",
+				Value: 250100 + nbr, Source: "Lampi Collection"})
+			moe := 1000
+			for j, popType := range []string{"all", "adults", "citizens", "cvap"} {
+				m.AddFact(gTestCongress, store.Fact{District: d, Type: popType,
+					Value: 700000 - 100000*j, MarginOfError: &moe, Source: "ACS"})
+			}
+		}
+		m.SetStatePop(gTestCongress, state, store.StatePop{Value: 700000 * nbrSeats,
+			Source: "Census"})
+	}
+	m.AddIrregularity(gTestCongress, fmt.Sprintf("S%02d", nbrStates-1),
+		"state_with_overlapping_terms")
+	return m
+}
+
+// newTestServer returns a server for the API, backed by newTestStore's data.
+func newTestServer() *httptest.Server {
+	gStore = newTestStore()
+	cfg := defaultConfig()
+	cfg.AccessLog = false
+	return httptest.NewServer(newRouter(&cfg))
+}
+
+// getJSON gets the given path and decodes the response into result, if
+// the response has the wanted status code.
+func getJSON(t *testing.T, server *httptest.Server, path string, wantStatus int,
+	result interface{}) {
+
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%v: got status %v; want %v", path, resp.StatusCode, wantStatus)
+	}
+	if result == nil {
+		return
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		t.Fatalf("%v: %v", path, err)
+	}
+}
+
+func testDistrictFacts() apiclient.DistrictFacts {
+	moe := 1000
+	return apiclient.DistrictFacts{
+		"all":      {Value: 700000, Source: "ACS", MarginOfError: &moe},
+		"adults":   {Value: 600000, Source: "ACS", MarginOfError: &moe},
+		"citizens": {Value: 500000, Source: "ACS", MarginOfError: &moe},
+		"cvap":     {Value: 400000, Source: "ACS", MarginOfError: &moe},
+		"turnout": {Value: 250001, Source: "MIT Election Data", Sources: []*apiclient.Fact{
+			{Value: 250001, Source: "MIT Election Data"},
+			{Value: 250101, Source: "Lampi Collection"},
+		}},
+	}
+}
+
+func TestGetStates(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	var states map[string]*apiclient.State
+	getJSON(t, server, "/api/congresses/115/states", http.StatusOK, &states)
+	if len(states) != 50 {
+		t.Fatalf("Got %v states; want 50", len(states))
+	}
+
+	// regular state
+	state := states["S00"]
+	if state.Irregular() || len(state.Districts) != 9 {
+		t.Fatalf("Got irregularities %v and %v districts for S00", state.IrregularHow,
+			len(state.Districts))
+	}
+	if !reflect.DeepEqual(state.Districts[1], testDistrictFacts()) {
+		t.Errorf("Got %+v for S00-1", state.Districts[1])
+	}
+	if state.Aggregates == nil || state.Aggregates.NbrReps != 9 ||
+		state.Aggregates.Population == nil || state.Aggregates.Population.Value != 9*700000 {
+		t.Errorf("Got aggregates %+v for S00", state.Aggregates)
+	}
+
+	// irregular state
+	state = states["S49"]
+	if !reflect.DeepEqual(state.IrregularHow, []string{"state_with_overlapping_terms"}) {
+		t.Errorf("Got irregularities %v for S49", state.IrregularHow)
+	}
+	if len(state.Districts) > 0 {
+		t.Errorf("Got districts for irregular state S49")
+	}
+	if state.Aggregates == nil || state.Aggregates.NbrReps != 8 {
+		t.Errorf("Got aggregates %+v for S49", state.Aggregates)
+	}
+
+	// errors
+	getJSON(t, server, "/api/congresses/999/states", http.StatusNotFound, nil)
+	getJSON(t, server, "/api/congresses/abc/states", http.StatusBadRequest, nil)
+}
+
+func TestGetState(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	var state apiclient.State
+	getJSON(t, server, "/api/congresses/115/states/S00", http.StatusOK, &state)
+	if len(state.Districts) != 9 || !reflect.DeepEqual(state.Districts[1], testDistrictFacts()) {
+		t.Errorf("Got %+v for S00", state)
+	}
+
+	getJSON(t, server, "/api/congresses/115/states/ZZ", http.StatusNotFound, nil)
+	getJSON(t, server, "/api/congresses/999/states/S00", http.StatusNotFound, nil)
+}
+
+func TestExportStateGeoJSON(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	for _, nbr := range []int{1, 1, 3} {
+		gStore.(*store.Memory).AddDistrictShape(gTestCongress, store.DistrictShape{
+			District: store.District{State: "S00", Nbr: nbr},
+			Geometry: json.RawMessage(fmt.Sprintf(`{"type":"Point","coordinates":[%v,0]}`, nbr)),
+		})
+	}
+
+	var collection struct {
+		Features []struct {
+			Geometry *struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+			Properties struct {
+				District int                     `json:"district"`
+				Facts    apiclient.DistrictFacts `json:"facts"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	getJSON(t, server, "/api/congresses/115/states/S00.geojson", http.StatusOK, &collection)
+	if len(collection.Features) != 9 {
+		t.Fatalf("Got %v features; want 9", len(collection.Features))
+	}
+	wantTypes := map[int]string{1: "GeometryCollection", 3: "Point"}
+	for i, feature := range collection.Features {
+		props := feature.Properties
+		if props.District != i+1 || props.Facts.Turnout() == nil {
+			t.Errorf("Got %+v for feature %v", props, i)
+		}
+		gotType := ""
+		if feature.Geometry != nil {
+			gotType = feature.Geometry.Type
+		}
+		if gotType != wantTypes[props.District] {
+			t.Errorf("Got geometry %q for S00-%v; want %q", gotType, props.District,
+				wantTypes[props.District])
+		}
+	}
+}
+
+func TestAggregateIncompleteState(t *testing.T) {
+	districts := []store.District{{State: "AA", Nbr: 1}, {State: "AA", Nbr: 2}}
+	moe := 1000
+	allFacts := map[store.District]districtFacts{
+		districts[0]: {"all": &factWithMoe{fact: fact{Value: 700000, Source: "ACS"}, MarginOfError: moe}},
+	}
+	aggs := aggregateState(districts, allFacts)
+	perRep := aggs.PeoplePerRep
+	if aggs.NbrReps != 2 || perRep == nil || perRep.Complete || perRep.NbrDistricts != 1 ||
+		perRep.Value != 700000 || *perRep.MarginOfError != 1000 {
+
+		t.Errorf("Got %+v and people per rep %+v", aggs, perRep)
+	}
+}
+
+func TestGetStateIrregularities(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	type irregularities struct {
+		Irregular      bool `json:"irregular"`
+		Irregularities []struct {
+			Type        string `json:"type"`
+			Explanation string `json:"explanation"`
+		} `json:"irregularities"`
+	}
+
+	var irregs irregularities
+	getJSON(t, server, "/api/congresses/115/states/S49/irregularities", http.StatusOK, &irregs)
+	if !irregs.Irregular || len(irregs.Irregularities) != 1 ||
+		irregs.Irregularities[0].Type != "state_with_overlapping_terms" ||
+		len(irregs.Irregularities[0].Explanation) == 0 {
+
+		t.Errorf("Got %+v for S49", irregs)
+	}
+
+	irregs = irregularities{}
+	getJSON(t, server, "/api/congresses/115/states/S00/irregularities", http.StatusOK, &irregs)
+	if irregs.Irregular || irregs.Irregularities == nil || len(irregs.Irregularities) > 0 {
+		t.Errorf("Got %+v for S00", irregs)
+	}
+
+	getJSON(t, server, "/api/congresses/115/states/ZZ/irregularities",
+		http.StatusNotFound, nil)
+	getJSON(t, server, "/api/congresses/999/states/S00/irregularities",
+		http.StatusNotFound, nil)
+}
+
+func TestGetDistrict(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	var facts apiclient.DistrictFacts
+	getJSON(t, server, "/api/congresses/115/states/S00/districts/1", http.StatusOK, &facts)
+	if !reflect.DeepEqual(facts, testDistrictFacts()) {
+		t.Errorf("Got %+v for S00-1", facts)
+	}
+
+	getJSON(t, server, "/api/congresses/115/states/S00/districts/99", http.StatusNotFound, nil)
+	getJSON(t, server, "/api/congresses/115/states/ZZ/districts/1", http.StatusNotFound, nil)
+	getJSON(t, server, "/api/congresses/999/states/S00/districts/1", http.StatusNotFound, nil)
+	getJSON(t, server, "/api/congresses/115/states/S00/districts/abc",
+		http.StatusNotFound, nil)
+}
+
+func TestGetApportionment(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	/* The 115th Congress was apportioned by the 2010 census, to 435 seats */
+	var appor apiclient.Apportionment
+	getJSON(t, server, "/api/congresses/115/apportionment", http.StatusOK, &appor)
+	if appor.Size != 435 || len(appor.States) != 50 {
+		t.Fatalf("Got size %v and %v states", appor.Size, len(appor.States))
+	}
+	state := appor.States["S00"]
+	if len(state.Districts) != 9 || state.Apportionment == nil ||
+		state.Apportionment.Seats != 9 || state.Apportionment.ActualSeats != 9 {
+
+		t.Errorf("Got %+v for S00", state)
+	}
+
+	getJSON(t, server, "/api/congresses/115/apportionment?size=500", http.StatusOK, &appor)
+	if appor.Size != 500 {
+		t.Errorf("Got size %v; want 500", appor.Size)
+	}
+}
+
+func TestGetProposals(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	m := gStore.(*store.Memory)
+	for i := 0; i < 50; i++ {
+		m.SetCensusStatePop(2010, fmt.Sprintf("S%02d", i),
+			store.StatePop{Value: 1000000 + 10000*i, Source: "Census"})
+	}
+
+	var proposals struct {
+		Censuses map[string]struct {
+			Proposals map[string]struct {
+				Size int `json:"size"`
+			} `json:"proposals"`
+		} `json:"censuses"`
+	}
+	getJSON(t, server, "/api/proposals?rules=size-435", http.StatusOK, &proposals)
+	if size := proposals.Censuses["2010"].Proposals["size-435"].Size; size != 435 {
+		t.Errorf("Got size %v; want 435", size)
+	}
+
+	/* fewer seats than states */
+	getJSON(t, server, "/api/proposals?rules=size-49", http.StatusBadRequest, nil)
+}
+
+func TestGetInequality(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	/* An uncontested race can have no votes counted */
+	m := gStore.(*store.Memory)
+	m.AddCongress(store.Congress{Nbr: 116, StartYear: 2019})
+	for nbr, turnout := range []int{0, 200000, 300000} {
+		d := store.District{State: "S00", Nbr: nbr + 1}
+		m.AddDistrict(116, d)
+		m.AddFact(116, store.Fact{District: d, Type: "turnout", Value: turnout,
+			Source: "MIT Election Data"})
+	}
+
+	var inequality struct {
+		Turnout *struct {
+			NbrDistricts int     `json:"nbrDistricts"`
+			MaxMinRatio  float64 `json:"maxMinRatio"`
+		} `json:"turnout"`
+	}
+	getJSON(t, server, "/api/congresses/116/inequality", http.StatusOK, &inequality)
+	if inequality.Turnout == nil || inequality.Turnout.NbrDistricts != 2 ||
+		inequality.Turnout.MaxMinRatio != 1.5 {
+
+		t.Errorf("Got turnout inequality %+v", inequality.Turnout)
+	}
+}
+
+func TestCheckReadiness(t *testing.T) {
+	ctx := context.Background()
+	failedCheck := func(info *readinessInfo) string {
+		for _, check := range info.Checks {
+			if !check.OK {
+				return check.Name
+			}
+		}
+		return ""
+	}
+
+	gStore = newTestStore()
+	if info := checkReadiness(ctx, 0); !info.Ready {
+		t.Errorf("Not ready: %v", failedCheck(info))
+	}
+
+	if info := checkReadiness(ctx, time.Nanosecond); info.Ready || failedCheck(info) != "freshness" {
+		t.Errorf("Got ready %v and failed check %q", info.Ready, failedCheck(info))
+	}
+
+	m := store.NewMemory()
+	gStore = m
+	m.AddCongress(store.Congress{Nbr: gTestCongress, StartYear: 2017})
+	m.AddCongress(store.Congress{Nbr: gTestCongress + 1, StartYear: 2019})
+	if info := checkReadiness(ctx, 0); failedCheck(info) != "districts" {
+		t.Errorf("Got failed check %q; want districts", failedCheck(info))
+	}
+	m.AddDistrict(gTestCongress, store.District{State: "S00", Nbr: 1})
+	if info := checkReadiness(ctx, 0); failedCheck(info) != "facts" {
+		t.Errorf("Got failed check %q; want facts", failedCheck(info))
+	}
+}
+
+func TestHead(t *testing.T) {
+	gStore = newTestStore()
+	cfg := defaultConfig()
+	cfg.AccessLog = false
+	router := newRouter(&cfg)
+	serve := func(method, path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if len(etag) > 0 {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	cases := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/api/congresses/115/states", http.StatusOK},
+		{"/api/congresses/115/states.csv", http.StatusOK},
+		{"/api/congresses/115/states/S00/districts/1", http.StatusOK},
+		{"/api/congresses/999/states", http.StatusNotFound},
+		{"/api/congresses/999/states.csv", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		get := serve("GET", c.path, "")
+		for i := 0; i < 2; i++ { // the second one is cached
+			head := serve("HEAD", c.path, "")
+			if head.Code != c.wantStatus {
+				t.Errorf("HEAD %v: got status %v; want %v", c.path, head.Code, c.wantStatus)
+			}
+			if head.Body.Len() > 0 {
+				t.Errorf("HEAD %v: got a body", c.path)
+			}
+			for _, name := range []string{gContentTypeHeader, "ETag"} {
+				if head.Header().Get(name) != get.Header().Get(name) {
+					t.Errorf("HEAD %v: got %v %q; want %q", c.path, name,
+						head.Header().Get(name), get.Header().Get(name))
+				}
+			}
+		}
+		if c.wantStatus != http.StatusOK {
+			/* Errors mustn't be cached */
+			for _, name := range []string{"ETag", "Cache-Control", "Last-Modified"} {
+				if len(get.Header().Get(name)) > 0 {
+					t.Errorf("GET %v: got %v %q on an error", c.path, name, get.Header().Get(name))
+				}
+			}
+			continue
+		}
+
+		head := serve("HEAD", c.path, get.Header().Get("ETag"))
+		if head.Code != http.StatusNotModified || head.Body.Len() > 0 {
+			t.Errorf("Conditional HEAD %v: got status %v and %v bytes", c.path, head.Code,
+				head.Body.Len())
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package metrics keeps counters, gauges, and histograms and writes them in
+// the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const gContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are histogram buckets (in seconds) suitable for the latency
+// of requests and DB queries.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry is a set of metrics.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (self *Registry) add(m metric) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.metrics = append(self.metrics, m)
+}
+
+// WriteText writes all the metrics in the Prometheus text format.
+func (self *Registry) WriteText(w io.Writer) error {
+	self.mu.Lock()
+	metrics := append([]metric(nil), self.metrics...)
+	self.mu.Unlock()
+
+	buf := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buf)
+	}
+	return buf.Flush()
+}
+
+// Handler returns a handler that serves the metrics.
+func (self *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("Content-Type", gContentType)
+		self.WriteText(resp)
+	})
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %v %v\n", name, strings.Replace(help, "\n", " ", -1))
+	fmt.Fprintf(w, "# TYPE %v %v\n", name, typ)
+}
+
+var gLabelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels returns the given labels as "{name="value",...}", or "" if
+// there are none.
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf(`%v="%v"`, name, gLabelValueReplacer.Replace(values[i]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// seriesKey returns a key for the series with the given label values.
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func checkLabels(name string, labelNames []string, labelValues []string) {
+	if len(labelValues) != len(labelNames) {
+		panic(fmt.Sprintf("%v: expected %v label values but got %v", name,
+			len(labelNames), len(labelValues)))
+	}
+}
+
+// CounterVec is a set of counters, one for each combination of label values.
+type CounterVec struct {
+	name       string
+	help       string
+	labelNames []string
+	mu         sync.Mutex
+	values     map[string]float64
+	labels     map[string][]string
+}
+
+func (self *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		values:     make(map[string]float64),
+		labels:     make(map[string][]string),
+	}
+	self.add(c)
+	return c
+}
+
+// Add adds v (which must not be negative) to the counter with the given
+// label values.
+func (self *CounterVec) Add(v float64, labelValues ...string) {
+	checkLabels(self.name, self.labelNames, labelValues)
+	key := seriesKey(labelValues)
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if _, ok := self.labels[key]; !ok {
+		self.labels[key] = append([]string(nil), labelValues...)
+	}
+	self.values[key] += v
+}
+
+func (self *CounterVec) Inc(labelValues ...string) {
+	self.Add(1, labelValues...)
+}
+
+func (self *CounterVec) write(w *bufio.Writer) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	writeHeader(w, self.name, self.help, "counter")
+	keys := make([]string, 0, len(self.values))
+	for key := range self.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%v%v %v\n", self.name,
+			formatLabels(self.labelNames, self.labels[key]), formatValue(self.values[key]))
+	}
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 /* per bucket, not cumulative */
+	count       uint64
+	sum         float64
+}
+
+// HistogramVec is a set of histograms, one for each combination of label
+// values.
+type HistogramVec struct {
+	name       string
+	help       string
+	buckets    []float64 /* upper bounds, ascending */
+	labelNames []string
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+func (self *Registry) NewHistogramVec(name, help string, buckets []float64,
+	labelNames ...string) *HistogramVec {
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{
+		name:       name,
+		help:       help,
+		buckets:    sorted,
+		labelNames: labelNames,
+		series:     make(map[string]*histogramSeries),
+	}
+	self.add(h)
+	return h
+}
+
+// Observe adds a value to the histogram with the given label values.
+func (self *HistogramVec) Observe(v float64, labelValues ...string) {
+	checkLabels(self.name, self.labelNames, labelValues)
+	key := seriesKey(labelValues)
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	s, ok := self.series[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(self.buckets)),
+		}
+		self.series[key] = s
+	}
+	if i := sort.SearchFloat64s(self.buckets, v); i < len(self.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (self *HistogramVec) write(w *bufio.Writer) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	writeHeader(w, self.name, self.help, "histogram")
+	keys := make([]string, 0, len(self.series))
+	for key := range self.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	bucketLabelNames := append(append([]string(nil), self.labelNames...), "le")
+	for _, key := range keys {
+		s := self.series[key]
+		var cumCount uint64
+		for i, bound := range self.buckets {
+			cumCount += s.counts[i]
+			labels := formatLabels(bucketLabelNames,
+				append(append([]string(nil), s.labelValues...), formatValue(bound)))
+			fmt.Fprintf(w, "%v_bucket%v %v\n", self.name, labels, cumCount)
+		}
+		labels := formatLabels(bucketLabelNames,
+			append(append([]string(nil), s.labelValues...), "+Inf"))
+		fmt.Fprintf(w, "%v_bucket%v %v\n", self.name, labels, s.count)
+		labels = formatLabels(self.labelNames, s.labelValues)
+		fmt.Fprintf(w, "%v_sum%v %v\n", self.name, labels, formatValue(s.sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", self.name, labels, s.count)
+	}
+}
+
+// funcMetric is a gauge or counter whose value is got when the metrics are
+// written.
+type funcMetric struct {
+	name string
+	help string
+	typ  string
+	f    func() float64
+}
+
+// NewGaugeFunc adds a gauge whose value is got by calling f.
+func (self *Registry) NewGaugeFunc(name, help string, f func() float64) {
+	self.add(&funcMetric{name: name, help: help, typ: "gauge", f: f})
+}
+
+// NewCounterFunc adds a counter whose value is got by calling f.
+func (self *Registry) NewCounterFunc(name, help string, f func() float64) {
+	self.add(&funcMetric{name: name, help: help, typ: "counter", f: f})
+}
+
+func (self *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, self.name, self.help, self.typ)
+	fmt.Fprintf(w, "%v %v\n", self.name, formatValue(self.f()))
+}
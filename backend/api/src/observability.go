@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strconv"
+	"time"
+
+	"expandourhouse.com/api/metrics"
+	"expandourhouse.com/api/store"
+	"github.com/gorilla/mux"
+)
+
+/*
+Every request is timed and counted by route (the route's path template, so
+that e.g. all congresses' states views are one route), and every call to the
+store is timed and counted by method.  The metrics are served at /metrics in
+the Prometheus text format.  Each request also gets a line in the access
+log, with the congress it was about, so that slow congresses can be found.
+*/
+
+var gMetrics = metrics.NewRegistry()
+
+var gRequestDurations = gMetrics.NewHistogramVec("eoh_api_request_duration_seconds",
+	"Time taken to serve requests, by route", metrics.DefaultBuckets,
+	"method", "route", "status")
+
+var gStoreCallDurations = gMetrics.NewHistogramVec("eoh_api_store_call_duration_seconds",
+	"Time taken by calls to the store (i.e., DB queries), by store method",
+	metrics.DefaultBuckets, "method")
+
+var gStoreCallErrors = gMetrics.NewCounterVec("eoh_api_store_call_errors_total",
+	"Calls to the store that failed, by store method", "method")
+
+// registerPoolMetrics adds metrics about the given store's pool of DB
+// connections, if it has one.
+func registerPoolMetrics(s store.Store) {
+	pooled, ok := s.(store.Pooled)
+	if !ok {
+		return
+	}
+	stat := func(f func(stats sql.DBStats) float64) func() float64 {
+		return func() float64 { return f(pooled.PoolStats()) }
+	}
+	gMetrics.NewGaugeFunc("eoh_api_db_max_open_connections",
+		"Max open DB connections (0 = unlimited)",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	gMetrics.NewGaugeFunc("eoh_api_db_open_connections", "Open DB connections",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	gMetrics.NewGaugeFunc("eoh_api_db_in_use_connections", "DB connections in use",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	gMetrics.NewGaugeFunc("eoh_api_db_idle_connections", "Idle DB connections",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	gMetrics.NewCounterFunc("eoh_api_db_waits_total",
+		"Times we waited for a DB connection",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	gMetrics.NewCounterFunc("eoh_api_db_wait_seconds_total",
+		"Total time spent waiting for DB connections",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+}
+
+// instrumentedStore is a store that times and counts the calls to another
+// store.  For the ForEach methods, the time includes the callbacks.
+type instrumentedStore struct {
+	store.Store
+}
+
+func (self *instrumentedStore) observe(method string, start time.Time, err error) {
+	gStoreCallDurations.Observe(time.Since(start).Seconds(), method)
+	if err != nil {
+		gStoreCallErrors.Inc(method)
+	}
+}
+
+func (self *instrumentedStore) Congresses(ctx context.Context) (result []store.Congress,
+	err error) {
+
+	defer func(start time.Time) { self.observe("Congresses", start, err) }(time.Now())
+	return self.Store.Congresses(ctx)
+}
+
+func (self *instrumentedStore) States(ctx context.Context, congress int) (result []string,
+	err error) {
+
+	defer func(start time.Time) { self.observe("States", start, err) }(time.Now())
+	return self.Store.States(ctx, congress)
+}
+
+func (self *instrumentedStore) Districts(ctx context.Context,
+	congress int) (result []store.District, err error) {
+
+	defer func(start time.Time) { self.observe("Districts", start, err) }(time.Now())
+	return self.Store.Districts(ctx, congress)
+}
+
+func (self *instrumentedStore) Irregularities(ctx context.Context,
+	congress int) (result map[string][]string, err error) {
+
+	defer func(start time.Time) { self.observe("Irregularities", start, err) }(time.Now())
+	return self.Store.Irregularities(ctx, congress)
+}
+
+func (self *instrumentedStore) ForEachFact(ctx context.Context, scope store.Scope,
+	f func(store.Fact) error) (err error) {
+
+	defer func(start time.Time) { self.observe("ForEachFact", start, err) }(time.Now())
+	return self.Store.ForEachFact(ctx, scope, f)
+}
+
+func (self *instrumentedStore) StatePops(ctx context.Context,
+	congress int) (result map[string]store.StatePop, err error) {
+
+	defer func(start time.Time) { self.observe("StatePops", start, err) }(time.Now())
+	return self.Store.StatePops(ctx, congress)
+}
+
+func (self *instrumentedStore) CensusStatePops(ctx context.Context,
+	censusYear int) (result map[string]store.StatePop, err error) {
+
+	defer func(start time.Time) { self.observe("CensusStatePops", start, err) }(time.Now())
+	return self.Store.CensusStatePops(ctx, censusYear)
+}
+
+func (self *instrumentedStore) StatePopProjections(
+	ctx context.Context) (result []store.StatePopProjection, err error) {
+
+	defer func(start time.Time) { self.observe("StatePopProjections", start, err) }(time.Now())
+	return self.Store.StatePopProjections(ctx)
+}
+
+func (self *instrumentedStore) RepTerms(ctx context.Context,
+	scope store.Scope) (result []store.RepTerm, err error) {
+
+	defer func(start time.Time) { self.observe("RepTerms", start, err) }(time.Now())
+	return self.Store.RepTerms(ctx, scope)
+}
+
+func (self *instrumentedStore) ForEachDistrictShape(ctx context.Context, scope store.Scope,
+	f func(store.DistrictShape) error) (err error) {
+
+	defer func(start time.Time) { self.observe("ForEachDistrictShape", start, err) }(time.Now())
+	return self.Store.ForEachDistrictShape(ctx, scope, f)
+}
+
+func (self *instrumentedStore) StateShapes(ctx context.Context,
+	congress int) (result []store.StateShape, err error) {
+
+	defer func(start time.Time) { self.observe("StateShapes", start, err) }(time.Now())
+	return self.Store.StateShapes(ctx, congress)
+}
+
+func (self *instrumentedStore) DataVersion(ctx context.Context) (result store.DataVersion,
+	err error) {
+
+	defer func(start time.Time) { self.observe("DataVersion", start, err) }(time.Now())
+	return self.Store.DataVersion(ctx)
+}
+
+func (self *instrumentedStore) Health(ctx context.Context) (result store.Health, err error) {
+	defer func(start time.Time) { self.observe("Health", start, err) }(time.Now())
+	return self.Store.Health(ctx)
+}
+
+// statusRecorder is an http.ResponseWriter that remembers the status and the
+// size of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	nbrBytes   int64
+}
+
+func (self *statusRecorder) WriteHeader(statusCode int) {
+	if self.statusCode == 0 {
+		self.statusCode = statusCode
+	}
+	self.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (self *statusRecorder) Write(data []byte) (int, error) {
+	if self.statusCode == 0 {
+		self.statusCode = http.StatusOK
+	}
+	n, err := self.ResponseWriter.Write(data)
+	self.nbrBytes += int64(n)
+	return n, err
+}
+
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	RequestID  string  `json:"requestId"`
+	RemoteAddr string  `json:"remoteAddr"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Route      string  `json:"route"`
+	Congress   *int    `json:"congress,omitempty"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"durationMs"`
+	UserAgent  string  `json:"userAgent,omitempty"`
+}
+
+var gAccessLog = log.New(os.Stdout, "", 0)
+
+// observeRequests times and counts the requests served by next, and writes
+// them to the access log if accessLog is true.  Requests are matched against
+// router to find their routes.
+func observeRequests(router *mux.Router, next http.Handler, accessLog bool) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: resp}
+		next.ServeHTTP(rec, req)
+		duration := time.Since(start)
+		if rec.statusCode == 0 {
+			rec.statusCode = http.StatusOK
+		}
+
+		// find route
+		route := "unmatched"
+		var congress *int
+		var match mux.RouteMatch
+		if router.Match(req, &match) && match.Route != nil {
+			if tmpl, err := match.Route.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+			if nbr, err := strconv.Atoi(match.Vars["congress"]); err == nil {
+				congress = &nbr
+			}
+		}
+
+		gRequestDurations.Observe(duration.Seconds(), req.Method, route,
+			strconv.Itoa(rec.statusCode))
+		if !accessLog {
+			return
+		}
+		remoteAddr, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			remoteAddr = req.RemoteAddr
+		}
+		data, err := json.Marshal(&accessLogEntry{
+			Time:       start.UTC().Format(time.RFC3339Nano),
+			RequestID:  requestID(req.Context()),
+			RemoteAddr: remoteAddr,
+			Method:     req.Method,
+			Path:       req.URL.RequestURI(),
+			Route:      route,
+			Congress:   congress,
+			Status:     rec.statusCode,
+			Bytes:      rec.nbrBytes,
+			DurationMs: float64(duration.Microseconds()) / 1000,
+			UserAgent:  req.UserAgent(),
+		})
+		if err == nil {
+			gAccessLog.Print(string(data))
+		}
+	})
+}
+
+// servePprof serves the pprof profiles on the given address.  It should be
+// a private address, since profiles reveal a lot about the server.
+func servePprof(addr string) {
+	pprofMux := http.NewServeMux()
+	pprofMux.HandleFunc("/debug/pprof/", pprof.Index)
+	pprofMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	pprofMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	pprofMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	pprofMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	log.Printf("Serving pprof on %v", addr)
+	if err := http.ListenAndServe(addr, pprofMux); err != nil {
+		log.Printf("pprof server failed: %v", err)
+	}
+}
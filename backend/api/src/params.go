@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// params reads a request's route vars and query params, collecting the
+// problems with them so that they can all be reported at once.
+type params struct {
+	req      *http.Request
+	problems []paramError
+}
+
+func newParams(req *http.Request) *params {
+	return &params{req: req}
+}
+
+func (self *params) addProblem(param string, format string, args ...interface{}) {
+	self.problems = append(self.problems, paramError{
+		Param:   param,
+		Problem: fmt.Sprintf(format, args...),
+	})
+}
+
+func (self *params) parseInt(param string, s string) int {
+	val, err := strconv.Atoi(s)
+	if err != nil {
+		self.addProblem(param, "Must be an integer")
+	}
+	return val
+}
+
+// routeInt returns the given route var as an int.
+func (self *params) routeInt(name string) int {
+	return self.parseInt(name, mux.Vars(self.req)[name])
+}
+
+// routeString returns the given route var.
+func (self *params) routeString(name string) string {
+	return mux.Vars(self.req)[name]
+}
+
+// queryString returns the given query param, or "" if it is absent.
+func (self *params) queryString(name string) string {
+	return self.req.URL.Query().Get(name)
+}
+
+// requiredQueryInt returns the given query param as an int.
+func (self *params) requiredQueryInt(name string) int {
+	s := self.queryString(name)
+	if len(s) == 0 {
+		self.addProblem(name, "Required")
+		return 0
+	}
+	return self.parseInt(name, s)
+}
+
+// queryInt returns the given query param as an int, or defaultVal if it is
+// absent.  The value must be between min and max.
+func (self *params) queryInt(name string, defaultVal, min, max int) int {
+	s := self.queryString(name)
+	if len(s) == 0 {
+		return defaultVal
+	}
+	val, err := strconv.Atoi(s)
+	if err != nil {
+		self.addProblem(name, "Must be an integer")
+		return defaultVal
+	}
+	if val < min || val > max {
+		self.addProblem(name, "Must be between %v and %v", min, max)
+		return defaultVal
+	}
+	return val
+}
+
+// requiredQueryFloat returns the given query param as a float, which must be
+// between min and max.
+func (self *params) requiredQueryFloat(name string, min, max float64) float64 {
+	s := self.queryString(name)
+	if len(s) == 0 {
+		self.addProblem(name, "Required")
+		return 0
+	}
+	val, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(val) || math.IsInf(val, 0) {
+		self.addProblem(name, "Must be a number")
+		return 0
+	}
+	if val < min || val > max {
+		self.addProblem(name, "Must be between %v and %v", min, max)
+		return 0
+	}
+	return val
+}
+
+// err returns an error describing the problems with the params, or nil if
+// there are none.
+func (self *params) err() error {
+	if len(self.problems) == 0 {
+		return nil
+	}
+	return errInvalidParams(self.problems)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequiredQueryFloat(t *testing.T) {
+	cases := []struct {
+		query string
+		want  float64
+		ok    bool
+	}{
+		{"lat=38.9", 38.9, true},
+		{"lat=-90", -90, true},
+		{"lat=", 0, false},
+		{"", 0, false},
+		{"lat=abc", 0, false},
+		{"lat=91", 0, false},
+		{"lat=NaN", 0, false},
+		{"lat=nan", 0, false},
+		{"lat=Inf", 0, false},
+		{"lat=-Infinity", 0, false},
+	}
+	for _, c := range cases {
+		p := newParams(httptest.NewRequest("GET", "/?"+c.query, nil))
+		got := p.requiredQueryFloat("lat", -90, 90)
+		if ok := p.err() == nil; ok != c.ok || got != c.want {
+			t.Errorf("%q: got %v (ok: %v); want %v (ok: %v)", c.query, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+
+	"expandourhouse.com/lib/apportionment"
+)
+
+/*
+A priority list is the order in which a divisor method hands out the seats
+beyond each state's first, given a census's populations.  It answers
+questions like "which state would get seat 436?"
+
+The seats just below and just above the actual House size are flagged:
+the states with the last few seats ("lastIn") are the ones that would lose
+seats first if the House shrank, and the states with the next few
+("firstOut") are the ones that would gain seats first if it grew.
+*/
+
+// gNbrNearCutoff is the number of seats on each side of the actual House
+// size that we flag.
+const gNbrNearCutoff = 5
+
+type prioritySeat struct {
+	HouseSize int     `json:"houseSize"` /* once this seat is assigned */
+	State     string  `json:"state"`
+	StateSeat int     `json:"stateSeat"` /* the state's seats once it gets this one */
+	Priority  float64 `json:"priority"`
+
+	// NearCutoff is "lastIn" or "firstOut" for seats near the actual House
+	// size.
+	NearCutoff string `json:"nearCutoff,omitempty"`
+}
+
+type priorityList struct {
+	CensusYear       int                  `json:"censusYear"`
+	Method           apportionment.Method `json:"method"`
+	PopulationSource string               `json:"populationSource"`
+	ActualSize       *int                 `json:"actualSize"` /* nil == unknown */
+	Through          int                  `json:"through"`
+	Tied             []string             `json:"tied,omitempty"`
+	Seats            []*prioritySeat      `json:"seats"`
+	LastIn           []*prioritySeat      `json:"lastIn"`
+	FirstOut         []*prioritySeat      `json:"firstOut"`
+}
+
+// makePrioritySeats makes the entries of a priority list from the given
+// seat assignments for states with the given populations.  It flags the
+// seats near the given House size, unless it's 0, and returns them as well.
+func makePrioritySeats(pops map[string]int, assignments []apportionment.Seat,
+	size int) (seats, lastIn, firstOut []*prioritySeat) {
+
+	seats = make([]*prioritySeat, 0, len(assignments))
+	lastIn, firstOut = []*prioritySeat{}, []*prioritySeat{}
+	stateSeats := make(map[string]int)
+	for state := range pops {
+		stateSeats[state] = 1
+	}
+	for _, assignment := range assignments {
+		stateSeats[assignment.State]++
+		seat := &prioritySeat{
+			HouseSize: assignment.Nbr,
+			State:     assignment.State,
+			StateSeat: stateSeats[assignment.State],
+			Priority:  assignment.Priority,
+		}
+		seats = append(seats, seat)
+
+		// flag seats near the cutoff
+		if size == 0 {
+			continue
+		}
+		if seat.HouseSize <= size && seat.HouseSize > size-gNbrNearCutoff {
+			seat.NearCutoff = "lastIn"
+			lastIn = append(lastIn, seat)
+		} else if seat.HouseSize > size && seat.HouseSize <= size+gNbrNearCutoff {
+			seat.NearCutoff = "firstOut"
+			firstOut = append(firstOut, seat)
+		}
+	}
+	return seats, lastIn, firstOut
+}
+
+func handleGetPriorityList(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	censusYear := p.routeInt("year")
+	through := p.queryInt("through", 0, 1, gMaxHouseSize) /* 0 == a bit past the actual size */
+	method := apportionment.HuntingtonHill
+	if s := p.queryString("method"); len(s) > 0 {
+		var err error
+		method, err = apportionment.ParseMethod(s)
+		if err != nil {
+			p.addProblem("method", "%v", err)
+		} else if !method.IsDivisorMethod() {
+			p.addProblem("method", "%v has no priority list", method)
+		}
+	}
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	// get populations
+	pops, err := gStore.CensusStatePops(req.Context(), censusYear)
+	if err != nil {
+		return err
+	}
+	if len(pops) == 0 {
+		return errNotFound("No population data for census %v", censusYear)
+	}
+	popValues := make(map[string]int)
+	sources := make(map[string]bool)
+	for state, pop := range pops {
+		popValues[state] = pop.Value
+		sources[pop.Source] = true
+	}
+
+	/* There was no apportionment after some censuses (e.g., 1920) */
+	actualSize := 0
+	if historical, ok := apportionment.Historical(censusYear); ok {
+		actualSize = historical.Size
+	}
+	if through == 0 {
+		through = actualSize + gNbrNearCutoff
+		if actualSize == 0 {
+			through = gFixedSize + gNbrNearCutoff
+		}
+	}
+
+	// apportion
+	res, err := apportionment.Apportion(popValues, through, method)
+	if err != nil {
+		return errBadRequest("%v", err)
+	}
+
+	// make result
+	result := priorityList{
+		CensusYear:       censusYear,
+		Method:           res.Method,
+		PopulationSource: joinSources(sources),
+		Through:          res.Size,
+		Tied:             res.Tied,
+	}
+	if actualSize > 0 {
+		result.ActualSize = &actualSize
+	}
+	result.Seats, result.LastIn, result.FirstOut = makePrioritySeats(popValues,
+		res.Assignments, actualSize)
+
+	// make response
+	return writeJSON(resp, result)
+}
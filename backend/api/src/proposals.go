@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"expandourhouse.com/lib/apportionment"
+	"expandourhouse.com/lib/proposals"
+)
+
+/*
+The Houses that proposed rules for the size of the House (see package
+proposals) would have produced after each census for which we have
+populations, compared with the actual sizes of the Houses of the congresses
+apportioned according to that census.
+*/
+
+type sizeComparison struct {
+	Congress   int `json:"congress"`
+	ActualSize int `json:"actualSize"`
+	Difference int `json:"difference"` /* proposed minus actual */
+}
+
+type proposalInfo struct {
+	Size        int               `json:"size"`
+	Seats       map[string]int    `json:"seats"`
+	Comparisons []*sizeComparison `json:"comparisons"`
+}
+
+type censusProposals struct {
+	PopulationSource string                   `json:"populationSource"`
+	Proposals        map[string]*proposalInfo `json:"proposals"`
+}
+
+type proposalsInfo struct {
+	Rules    map[string]string           `json:"rules"` /* names -> descriptions */
+	Censuses map[string]*censusProposals `json:"censuses"`
+}
+
+// parseRules parses a comma-separated list of rules.
+func parseRules(p *params, name string) []proposals.Rule {
+	s := p.queryString(name)
+	if len(s) == 0 {
+		return proposals.StandardRules()
+	}
+	var result []proposals.Rule
+	for _, part := range strings.Split(s, ",") {
+		rule, err := proposals.ParseRule(part)
+		if err != nil {
+			p.addProblem(name, "%v", err)
+			return nil
+		}
+		if rule.Kind == proposals.FixedSize && rule.Param > gMaxHouseSize {
+			p.addProblem(name, "House size must be at most %v", gMaxHouseSize)
+			return nil
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+// getActualSizes returns the sizes of the Houses of the congresses in the
+// DB, leaving out implausible ones.
+func getActualSizes(ctx context.Context) (map[int]int, error) {
+	cons, err := gStore.Congresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int]int)
+	for _, con := range cons {
+		nbrReps, err := getNbrReps(ctx, con.Nbr)
+		if err != nil {
+			return nil, err
+		}
+		if nbrReps > gMinPlausibleNbrReps {
+			result[con.Nbr] = nbrReps
+		}
+	}
+	return result, nil
+}
+
+func handleGetProposals(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	rules := parseRules(p, "rules")
+	if err := p.err(); err != nil {
+		return err
+	}
+
+	// get actual sizes
+	actualSizes, err := getActualSizes(req.Context())
+	if err != nil {
+		return err
+	}
+
+	result := proposalsInfo{
+		Rules:    make(map[string]string),
+		Censuses: make(map[string]*censusProposals),
+	}
+	for _, rule := range rules {
+		result.Rules[rule.String()] = rule.Description()
+	}
+
+	// apply rules to each census
+	historical := apportionment.HistoricalApportionments()
+	lastCensus := historical[len(historical)-1].CensusYear
+	for year := historical[0].CensusYear; year <= lastCensus; year += 10 {
+		pops, err := gStore.CensusStatePops(req.Context(), year)
+		if err != nil {
+			return err
+		}
+		if len(pops) == 0 {
+			continue
+		}
+		popValues := make(map[string]int)
+		sources := make(map[string]bool)
+		for state, pop := range pops {
+			popValues[state] = pop.Value
+			sources[pop.Source] = true
+		}
+
+		/* Compare with the congresses apportioned according to this census */
+		censusSizes := make(map[int]int)
+		for congress, size := range actualSizes {
+			if y, ok := apportionment.CensusForCongress(congress); ok && y == year {
+				censusSizes[congress] = size
+			}
+		}
+
+		census := censusProposals{
+			PopulationSource: joinSources(sources),
+			Proposals:        make(map[string]*proposalInfo),
+		}
+		for _, rule := range rules {
+			proposal, err := rule.Apply(popValues)
+			if err != nil {
+				return errBadRequest("%v census: %v", year, err)
+			}
+			info := proposalInfo{
+				Size:        proposal.Size,
+				Seats:       proposal.Seats,
+				Comparisons: []*sizeComparison{},
+			}
+			for _, c := range proposal.Compare(censusSizes) {
+				info.Comparisons = append(info.Comparisons, &sizeComparison{
+					Congress:   c.Congress,
+					ActualSize: c.ActualSize,
+					Difference: c.Difference,
+				})
+			}
+			census.Proposals[rule.String()] = &info
+		}
+		result.Censuses[fmt.Sprintf("%v", year)] = &census
+	}
+
+	// make response
+	return writeJSON(resp, result)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+
+	"expandourhouse.com/api/store"
+)
+
+const gDateFormat = "2006-01-02"
+
+type representativeInfo struct {
+	BioguideID string  `json:"bioguideId"`
+	FirstName  *string `json:"firstName"`
+	MiddleName *string `json:"middleName,omitempty"`
+	LastName   *string `json:"lastName"`
+	Party      *string `json:"party"`
+	District   *int    `json:"district"`
+	StartDate  string  `json:"startDate"`
+	EndDate    string  `json:"endDate"`
+}
+
+func makeRepresentativeInfos(terms []store.RepTerm) []*representativeInfo {
+	result := make([]*representativeInfo, 0, len(terms))
+	for _, term := range terms {
+		result = append(result, &representativeInfo{
+			BioguideID: term.BioguideID,
+			FirstName:  term.FirstName,
+			MiddleName: term.MiddleName,
+			LastName:   term.LastName,
+			Party:      term.Party,
+			District:   term.District,
+			StartDate:  term.StartDate.Format(gDateFormat),
+			EndDate:    term.EndDate.Format(gDateFormat),
+		})
+	}
+	return result
+}
+
+func handleGetStateRepresentatives(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	state := p.routeString("state")
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireState(req.Context(), congress, state); err != nil {
+		return err
+	}
+
+	// get terms
+	terms, err := gStore.RepTerms(req.Context(),
+		store.Scope{Congress: congress, State: state})
+	if err != nil {
+		return err
+	}
+
+	// make response
+	return writeJSON(resp, makeRepresentativeInfos(terms))
+}
+
+func handleGetDistrictRepresentatives(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	district := store.District{
+		State: p.routeString("state"),
+		Nbr:   p.routeInt("district"),
+	}
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireDistrict(req.Context(), congress, district); err != nil {
+		return err
+	}
+
+	// get terms
+	terms, err := gStore.RepTerms(req.Context(), store.Scope{
+		Congress: congress,
+		State:    district.State,
+		District: &district.Nbr,
+	})
+	if err != nil {
+		return err
+	}
+
+	// make response
+	return writeJSON(resp, makeRepresentativeInfos(terms))
+}
@@ -0,0 +1,79 @@
+// Package spatial finds the shapes that contain a point.
+package spatial
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+/*
+Index is a uniform grid over longitude and latitude.  Each cell lists the
+shapes whose bounding boxes overlap it, so a lookup only has to test the
+few shapes listed in the point's cell.
+*/
+
+// gCellSize is the width and height of the cells, in degrees.
+const gCellSize = 1.0
+
+type cell struct {
+	x, y int
+}
+
+type Index struct {
+	geoms  []orb.Geometry
+	bounds []orb.Bound
+	cells  map[cell][]int
+}
+
+func cellCoord(deg float64) int {
+	return int(math.Floor(deg / gCellSize))
+}
+
+// NewIndex returns an index of the given geometries, which should be
+// polygons or multipolygons.  Other geometries never contain any point.
+func NewIndex(geoms []orb.Geometry) *Index {
+	index := &Index{
+		geoms:  geoms,
+		bounds: make([]orb.Bound, len(geoms)),
+		cells:  make(map[cell][]int),
+	}
+	for i, geom := range geoms {
+		if geom == nil {
+			continue
+		}
+		bound := geom.Bound()
+		index.bounds[i] = bound
+		for x := cellCoord(bound.Min.Lon()); x <= cellCoord(bound.Max.Lon()); x++ {
+			for y := cellCoord(bound.Min.Lat()); y <= cellCoord(bound.Max.Lat()); y++ {
+				c := cell{x, y}
+				index.cells[c] = append(index.cells[c], i)
+			}
+		}
+	}
+	return index
+}
+
+func contains(geom orb.Geometry, pt orb.Point) bool {
+	switch g := geom.(type) {
+	case orb.Polygon:
+		return planar.PolygonContains(g, pt)
+	case orb.MultiPolygon:
+		return planar.MultiPolygonContains(g, pt)
+	default:
+		return false
+	}
+}
+
+// Containing returns the indices (in the slice given to NewIndex) of the
+// geometries that contain the given point, in ascending order.
+func (self *Index) Containing(pt orb.Point) []int {
+	var result []int
+	for _, i := range self.cells[cell{cellCoord(pt.Lon()), cellCoord(pt.Lat())}] {
+		if self.bounds[i].Contains(pt) && contains(self.geoms[i], pt) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
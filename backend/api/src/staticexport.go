@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"expandourhouse.com/lib/apportionment"
+)
+
+/*
+A static export is a directory tree with every JSON response of the API, so
+that the site can be served by a plain file server or CDN.  The response for
+a route is at the route's path plus "/index.json" (e.g.,
+api/congresses/113/states/index.json).  The responses are made by the API's
+own handlers, so they are the same as the live API's, byte for byte.
+
+Routes that take query params (compare, locate) and the CSV and GeoJSON
+exports are left out.
+
+The export is incremental: data-version.json records the data version that
+was exported, and if it is still current, nothing is done.  Otherwise only
+changed files are written, and files for things that no longer exist are
+deleted.  Delete data-version.json to force a full export.
+*/
+
+const gStaticExportWorkers = 8
+const gStaticVersionFile = "data-version.json"
+const gStaticIndexFile = "index.json"
+
+type staticVersion struct {
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type staticExporter struct {
+	handler http.Handler
+	dir     string
+
+	mu       sync.Mutex
+	written  map[string]bool /* paths of the files that should exist */
+	nbrNew   int
+	nbrSame  int
+	firstErr error
+}
+
+// staticPaths returns the paths of the routes to export.
+func staticPaths(ctx context.Context) ([]string, error) {
+	paths := []string{"/api/congresses", "/api/stats", "/api/proposals"}
+	for _, h := range apportionment.HistoricalApportionments() {
+		/* A census without population data is skipped like any other 404 */
+		paths = append(paths, fmt.Sprintf("/api/census/%v/priority-list", h.CensusYear))
+	}
+	projections, err := gStore.StatePopProjections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	forecastYears := make(map[int]bool)
+	for _, proj := range projections {
+		if !forecastYears[proj.Year] {
+			forecastYears[proj.Year] = true
+			paths = append(paths, fmt.Sprintf("/api/forecast/%v", proj.Year))
+		}
+	}
+	cons, err := gStore.Congresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, con := range cons {
+		conPath := fmt.Sprintf("/api/congresses/%v", con.Nbr)
+		paths = append(paths, conPath+"/states", conPath+"/stats",
+			conPath+"/apportionment", conPath+"/inequality")
+
+		states, err := gStore.States(ctx, con.Nbr)
+		if err != nil {
+			return nil, err
+		}
+		for _, state := range states {
+			statePath := fmt.Sprintf("%v/states/%v", conPath, state)
+			paths = append(paths, statePath, statePath+"/irregularities",
+				statePath+"/representatives")
+		}
+
+		districts, err := gStore.Districts(ctx, con.Nbr)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range districts {
+			distPath := fmt.Sprintf("%v/states/%v/districts/%v", conPath, d.State, d.Nbr)
+			paths = append(paths, distPath, distPath+"/representatives")
+		}
+	}
+	return paths, nil
+}
+
+// render returns the response for the given path, or nil if there is
+// nothing there.
+func (self *staticExporter) render(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", gJSONContentType)
+	buf := newResponseBuffer()
+	self.handler.ServeHTTP(buf, req.WithContext(ctx))
+	switch buf.statusCode {
+	case http.StatusOK:
+		return buf.body.Bytes(), nil
+	case http.StatusNotFound:
+		/* E.g., there is no apportionment without census data */
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%v: %v: %v", path, buf.statusCode, buf.body.String())
+	}
+}
+
+// writeFileIfChanged writes data to the given file, unless it already has
+// that data.  It returns whether it wrote the file.
+func writeFileIfChanged(path string, data []byte) (bool, error) {
+	if old, err := ioutil.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+
+	/* Write to a temp file first, so that readers never see half a file */
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return false, err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return false, err
+	}
+	return true, nil
+}
+
+func (self *staticExporter) export(ctx context.Context, path string) error {
+	data, err := self.render(ctx, path)
+	if err != nil || data == nil {
+		return err
+	}
+	filePath := filepath.Join(self.dir, filepath.FromSlash(path), gStaticIndexFile)
+	changed, err := writeFileIfChanged(filePath, data)
+	if err != nil {
+		return err
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.written[filePath] = true
+	if changed {
+		self.nbrNew++
+	} else {
+		self.nbrSame++
+	}
+	return nil
+}
+
+// removeStale deletes the exported files that weren't written this time,
+// and any directories left empty.
+func (self *staticExporter) removeStale() (int, error) {
+	root := filepath.Join(self.dir, "api")
+	var dirs []string
+	nbrRemoved := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		if info.Name() == gStaticIndexFile && !self.written[path] {
+			nbrRemoved++
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nbrRemoved, err
+	}
+
+	/* Remove children before parents */
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) == 0 {
+			os.Remove(dir)
+		}
+	}
+	return nbrRemoved, nil
+}
+
+// exportStatic writes the API's responses under the given directory.
+func exportStatic(ctx context.Context, cfg *config, dir string) error {
+	// check version
+	version, err := gStore.DataVersion(ctx)
+	if err != nil {
+		return err
+	}
+	versionPath := filepath.Join(dir, gStaticVersionFile)
+	if data, err := ioutil.ReadFile(versionPath); err == nil {
+		var exported staticVersion
+		if json.Unmarshal(data, &exported) == nil && exported.Version == version.Nbr {
+			log.Printf("Export is already at data version %v", version.Nbr)
+			return nil
+		}
+	}
+
+	// get paths
+	paths, err := staticPaths(ctx)
+	if err != nil {
+		return err
+	}
+
+	/* Don't log or cache the export's requests */
+	exportCfg := *cfg
+	exportCfg.AccessLog = false
+	exportCfg.CacheEntries = 0
+	exporter := staticExporter{
+		handler: newRouter(&exportCfg),
+		dir:     dir,
+		written: make(map[string]bool),
+	}
+
+	// export
+	log.Printf("Exporting %v routes at data version %v", len(paths), version.Nbr)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pathChan := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < gStaticExportWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range pathChan {
+				if err := exporter.export(ctx, path); err != nil {
+					exporter.mu.Lock()
+					if exporter.firstErr == nil {
+						exporter.firstErr = err
+						cancel()
+					}
+					exporter.mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		pathChan <- path
+	}
+	close(pathChan)
+	wg.Wait()
+	if exporter.firstErr != nil {
+		return exporter.firstErr
+	}
+
+	// clean up
+	nbrRemoved, err := exporter.removeStale()
+	if err != nil {
+		return err
+	}
+
+	/* Write the version last, so that an interrupted export is redone */
+	data, err := json.Marshal(&staticVersion{Version: version.Nbr, UpdatedAt: version.UpdatedAt})
+	if err != nil {
+		return err
+	}
+	if _, err = writeFileIfChanged(versionPath, data); err != nil {
+		return err
+	}
+	log.Printf("Wrote %v files, kept %v unchanged files, removed %v files",
+		exporter.nbrNew, exporter.nbrSame, nbrRemoved)
+	return nil
+}
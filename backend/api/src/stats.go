@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"expandourhouse.com/api/store"
+)
+
+/*
+These are the numbers that map-data's compute-stats puts in the app's
+stats.js, computed from the API's data instead.  "Regular" districts are
+those in states that aren't irregular (see store.Irregularities).
+*/
+
+// gVoterPercentiles are the percentiles of voters per regular district that
+// we report.
+var gVoterPercentiles = []int{10, 25, 50, 75, 90}
+
+/* Like compute-stats, we weed out implausible numbers of reps */
+const gMinPlausibleNbrReps = 10
+
+/* Like compute-stats, use 435 from the 61st Congress on */
+const gFixedSizeFirstCongress = 61
+const gFixedSize = 435
+
+type congressStats struct {
+	NbrReps          *int               `json:"nbrReps,omitempty"`
+	MedianVoters     *float64           `json:"medianVoters,omitempty"`
+	MinVoters        *float64           `json:"minVoters,omitempty"`
+	MaxVoters        *float64           `json:"maxVoters,omitempty"`
+	MeanVoters       *float64           `json:"meanVoters,omitempty"`
+	VoterPercentiles map[string]float64 `json:"voterPercentiles,omitempty"`
+}
+
+func (self *congressStats) empty() bool {
+	return self.NbrReps == nil && self.MedianVoters == nil
+}
+
+// percentile returns the pth percentile of the given sorted values,
+// interpolating between values when needed (so the 50th percentile is the
+// median).
+func percentile(sorted []int, p float64) float64 {
+	pos := float64(len(sorted)-1) * p / 100
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return float64(sorted[len(sorted)-1])
+	}
+	frac := pos - float64(i)
+	return float64(sorted[i]) + frac*float64(sorted[i+1]-sorted[i])
+}
+
+// getNbrReps returns the number of members of the House in the given
+// congress, or 0 if unknown.
+func getNbrReps(ctx context.Context, congress int) (int, error) {
+	if congress >= gFixedSizeFirstCongress {
+		return gFixedSize, nil
+	}
+
+	/* Count the reps who started on the first day */
+	terms, err := gStore.RepTerms(ctx, store.Scope{Congress: congress})
+	if err != nil || len(terms) == 0 {
+		return 0, err
+	}
+	firstDay := terms[0].StartDate
+	for _, term := range terms {
+		if term.StartDate.Before(firstDay) {
+			firstDay = term.StartDate
+		}
+	}
+	nbr := 0
+	for _, term := range terms {
+		if term.StartDate.Equal(firstDay) {
+			nbr++
+		}
+	}
+	return nbr, nil
+}
+
+// getValuesPerRegDistrict returns the values of the given types of facts
+// (e.g., "turnout") in each regular district in the given congress, by type,
+// each in ascending order.
+func getValuesPerRegDistrict(ctx context.Context, congress int,
+	factTypes ...string) (map[string][]int, error) {
+
+	irregularities, err := gStore.Irregularities(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	allFacts, err := getDistrictFacts(ctx, store.Scope{Congress: congress})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]int)
+	for d, distFacts := range allFacts {
+		if len(irregularities[d.State]) > 0 {
+			continue
+		}
+		for _, factType := range factTypes {
+			if f := factOf(distFacts, factType); f != nil {
+				result[factType] = append(result[factType], f.Value)
+			}
+		}
+	}
+	for _, values := range result {
+		sort.Ints(values)
+	}
+	return result, nil
+}
+
+// getVotersPerRegDistrict returns the turnout in each regular district in
+// the given congress, in ascending order.
+func getVotersPerRegDistrict(ctx context.Context, congress int) ([]int, error) {
+	values, err := getValuesPerRegDistrict(ctx, congress, "turnout")
+	if err != nil {
+		return nil, err
+	}
+	return values["turnout"], nil
+}
+
+func getCongressStats(ctx context.Context, congress int) (*congressStats, error) {
+	var stats congressStats
+
+	nbrReps, err := getNbrReps(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	if nbrReps > gMinPlausibleNbrReps {
+		stats.NbrReps = &nbrReps
+	}
+
+	voters, err := getVotersPerRegDistrict(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	if len(voters) == 0 {
+		return &stats, nil
+	}
+	median := percentile(voters, 50)
+	min := float64(voters[0])
+	max := float64(voters[len(voters)-1])
+	sum := 0.0
+	for _, v := range voters {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(voters))
+	stats.MedianVoters = &median
+	stats.MinVoters = &min
+	stats.MaxVoters = &max
+	stats.MeanVoters = &mean
+	stats.VoterPercentiles = make(map[string]float64)
+	for _, p := range gVoterPercentiles {
+		stats.VoterPercentiles[strconv.Itoa(p)] = percentile(voters, float64(p))
+	}
+	return &stats, nil
+}
+
+func handleGetCongressStats(resp http.ResponseWriter, req *http.Request) error {
+	// get vars
+	p := newParams(req)
+	congress := p.routeInt("congress")
+	if err := p.err(); err != nil {
+		return err
+	}
+	if err := requireCongress(req.Context(), congress); err != nil {
+		return err
+	}
+
+	// compute stats
+	result, err := getCongressStats(req.Context(), congress)
+	if err != nil {
+		return err
+	}
+
+	// make response
+	return writeJSON(resp, result)
+}
+
+func handleGetAllStats(resp http.ResponseWriter, req *http.Request) error {
+	// compute stats for each congress
+	cons, err := gStore.Congresses(req.Context())
+	if err != nil {
+		return err
+	}
+	result := make(map[string]*congressStats)
+	for _, con := range cons {
+		stats, err := getCongressStats(req.Context(), con.Nbr)
+		if err != nil {
+			return err
+		}
+		if !stats.empty() {
+			result[fmt.Sprintf("%v", con.Nbr)] = stats
+		}
+	}
+
+	// make response
+	return writeJSON(resp, result)
+}
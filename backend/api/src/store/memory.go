@@ -0,0 +1,365 @@
+package store
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is a store that keeps everything in memory.  It is meant for tests
+// and demos.  It is safe for concurrent use.
+type Memory struct {
+	mu             sync.RWMutex
+	congresses     map[int]Congress
+	districts      map[int][]District
+	irregularities map[int]map[string][]string
+	facts          map[int][]Fact
+	statePops      map[int]map[string]StatePop
+	censusPops     map[int]map[string]StatePop
+	projections    []StatePopProjection
+	repTerms       map[int][]RepTerm
+	districtShapes map[int][]DistrictShape
+	stateShapes    map[int][]StateShape
+	version        DataVersion
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		congresses:     make(map[int]Congress),
+		districts:      make(map[int][]District),
+		irregularities: make(map[int]map[string][]string),
+		facts:          make(map[int][]Fact),
+		statePops:      make(map[int]map[string]StatePop),
+		censusPops:     make(map[int]map[string]StatePop),
+		repTerms:       make(map[int][]RepTerm),
+		districtShapes: make(map[int][]DistrictShape),
+		stateShapes:    make(map[int][]StateShape),
+	}
+}
+
+func (self *Memory) AddCongress(con Congress) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	self.congresses[con.Nbr] = con
+}
+
+func (self *Memory) AddDistrict(congress int, d District) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	self.districts[congress] = append(self.districts[congress], d)
+}
+
+// AddIrregularity records that the given state is irregular in the given
+// way (one of Irregularities) in the given congress.
+func (self *Memory) AddIrregularity(congress int, state string, how string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	byState, ok := self.irregularities[congress]
+	if !ok {
+		byState = make(map[string][]string)
+		self.irregularities[congress] = byState
+	}
+	byState[state] = append(byState[state], how)
+}
+
+func (self *Memory) AddFact(congress int, f Fact) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	self.facts[congress] = append(self.facts[congress], f)
+}
+
+func (self *Memory) SetStatePop(congress int, state string, pop StatePop) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	byState, ok := self.statePops[congress]
+	if !ok {
+		byState = make(map[string]StatePop)
+		self.statePops[congress] = byState
+	}
+	byState[state] = pop
+}
+
+func (self *Memory) SetCensusStatePop(censusYear int, state string, pop StatePop) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	byState, ok := self.censusPops[censusYear]
+	if !ok {
+		byState = make(map[string]StatePop)
+		self.censusPops[censusYear] = byState
+	}
+	byState[state] = pop
+}
+
+func (self *Memory) AddStatePopProjection(proj StatePopProjection) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	self.projections = append(self.projections, proj)
+}
+
+func (self *Memory) AddRepTerm(congress int, term RepTerm) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	self.repTerms[congress] = append(self.repTerms[congress], term)
+}
+
+func (self *Memory) AddDistrictShape(congress int, shape DistrictShape) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	self.districtShapes[congress] = append(self.districtShapes[congress], shape)
+}
+
+func (self *Memory) AddStateShape(congress int, shape StateShape) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.bumpVersion()
+	self.stateShapes[congress] = append(self.stateShapes[congress], shape)
+}
+
+func (self *Memory) bumpVersion() {
+	self.version.Nbr++
+	self.version.UpdatedAt = time.Now()
+}
+
+func (self *Memory) DataVersion(ctx context.Context) (DataVersion, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.version, nil
+}
+
+func (self *Memory) Close() error {
+	return nil
+}
+
+func (self *Memory) Congresses(ctx context.Context) ([]Congress, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	var result []Congress
+	for _, con := range self.congresses {
+		result = append(result, con)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Nbr < result[j].Nbr })
+	return result, nil
+}
+
+func (self *Memory) States(ctx context.Context, congress int) ([]string, error) {
+	districts, _ := self.Districts(ctx, congress)
+	var result []string
+	for _, d := range districts {
+		if len(result) == 0 || result[len(result)-1] != d.State {
+			result = append(result, d.State)
+		}
+	}
+	return result, nil
+}
+
+func (self *Memory) Districts(ctx context.Context, congress int) ([]District, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	result := append([]District(nil), self.districts[congress]...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].State != result[j].State {
+			return result[i].State < result[j].State
+		}
+		return result[i].Nbr < result[j].Nbr
+	})
+	return result, nil
+}
+
+func (self *Memory) Irregularities(ctx context.Context,
+	congress int) (map[string][]string, error) {
+
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	result := make(map[string][]string)
+	for state, hows := range self.irregularities[congress] {
+		for _, irreg := range Irregularities {
+			for _, how := range hows {
+				if how == irreg {
+					result[state] = append(result[state], how)
+					break
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+func (self Scope) contains(d District) bool {
+	if len(self.State) > 0 && d.State != self.State {
+		return false
+	}
+	if self.District != nil && d.Nbr != *self.District {
+		return false
+	}
+	return true
+}
+
+func (self *Memory) ForEachFact(ctx context.Context, scope Scope,
+	f func(Fact) error) error {
+
+	self.mu.RLock()
+	var facts []Fact
+	for _, fact := range self.facts[scope.Congress] {
+		if scope.contains(fact.District) {
+			facts = append(facts, fact)
+		}
+	}
+	self.mu.RUnlock()
+
+	sort.SliceStable(facts, func(i, j int) bool {
+		a, b := facts[i].District, facts[j].District
+		if a.State != b.State {
+			return a.State < b.State
+		}
+		return a.Nbr < b.Nbr
+	})
+	for _, fact := range facts {
+		if err := f(fact); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *Memory) ForEachDistrictShape(ctx context.Context, scope Scope,
+	f func(DistrictShape) error) error {
+
+	self.mu.RLock()
+	var shapes []DistrictShape
+	for _, shape := range self.districtShapes[scope.Congress] {
+		if scope.contains(shape.District) {
+			shapes = append(shapes, shape)
+		}
+	}
+	self.mu.RUnlock()
+
+	sort.SliceStable(shapes, func(i, j int) bool {
+		a, b := shapes[i].District, shapes[j].District
+		if a.State != b.State {
+			return a.State < b.State
+		}
+		return a.Nbr < b.Nbr
+	})
+	for _, shape := range shapes {
+		if err := f(shape); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *Memory) StateShapes(ctx context.Context, congress int) ([]StateShape, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return append([]StateShape(nil), self.stateShapes[congress]...), nil
+}
+
+func (self *Memory) StatePops(ctx context.Context,
+	congress int) (map[string]StatePop, error) {
+
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	result := make(map[string]StatePop)
+	for state, pop := range self.statePops[congress] {
+		result[state] = pop
+	}
+	return result, nil
+}
+
+func (self *Memory) CensusStatePops(ctx context.Context,
+	censusYear int) (map[string]StatePop, error) {
+
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	result := make(map[string]StatePop)
+	for state, pop := range self.censusPops[censusYear] {
+		result[state] = pop
+	}
+	return result, nil
+}
+
+func (self *Memory) StatePopProjections(ctx context.Context) ([]StatePopProjection, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	result := append([]StatePopProjection(nil), self.projections...)
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Year != b.Year {
+			return a.Year < b.Year
+		}
+		if a.Scenario != b.Scenario {
+			return a.Scenario < b.Scenario
+		}
+		return a.State < b.State
+	})
+	return result, nil
+}
+
+func (self *Memory) RepTerms(ctx context.Context, scope Scope) ([]RepTerm, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	var result []RepTerm
+	for _, term := range self.repTerms[scope.Congress] {
+		if len(scope.State) > 0 && term.State != scope.State {
+			continue
+		}
+		if scope.District != nil &&
+			(term.District == nil || *term.District != *scope.District) {
+			continue
+		}
+		result = append(result, term)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.State != b.State {
+			return a.State < b.State
+		}
+		/* Unknown districts go last, as in SQL */
+		aDist, bDist := math.MaxInt32, math.MaxInt32
+		if a.District != nil {
+			aDist = *a.District
+		}
+		if b.District != nil {
+			bDist = *b.District
+		}
+		if aDist != bDist {
+			return aDist < bDist
+		}
+		return a.StartDate.Before(b.StartDate)
+	})
+	return result, nil
+}
+
+func (self *Memory) Health(ctx context.Context) (Health, error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	/* Everything was loaded when it was added */
+	var health Health
+	sources := make(map[string]bool)
+	for _, facts := range self.facts {
+		for _, fact := range facts {
+			sources[fact.Source] = true
+		}
+	}
+	for source := range sources {
+		health.Sources = append(health.Sources,
+			SourceLoad{Source: source, LoadedAt: self.version.UpdatedAt})
+	}
+	sort.Slice(health.Sources, func(i, j int) bool {
+		return health.Sources[i].Source < health.Sources[j].Source
+	})
+	return health, nil
+}
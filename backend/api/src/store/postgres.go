@@ -0,0 +1,415 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// OpenPostgres returns a store backed by the Postgres DB that loaddata fills.
+func OpenPostgres(connStr string, pool PoolOptions) (Store, error) {
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, err
+	}
+	pool.apply(db)
+	return &postgresStore{db: db}, nil
+}
+
+func (self *postgresStore) Close() error {
+	return self.db.Close()
+}
+
+func (self *postgresStore) PoolStats() sql.DBStats {
+	return self.db.Stats()
+}
+
+func (self *postgresStore) Congresses(ctx context.Context) ([]Congress, error) {
+	rows, err := self.db.QueryContext(ctx,
+		"SELECT nbr, start_year FROM congress ORDER BY nbr")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Congress
+	for rows.Next() {
+		var con Congress
+		if err = rows.Scan(&con.Nbr, &con.StartYear); err != nil {
+			return nil, err
+		}
+		result = append(result, con)
+	}
+	return result, rows.Err()
+}
+
+func (self *postgresStore) States(ctx context.Context, congress int) ([]string, error) {
+	sql := `SELECT DISTINCT state FROM house_district
+	WHERE congress_nbr = $1 ORDER BY state`
+	rows, err := self.db.QueryContext(ctx, sql, congress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var stateAbbr string
+		if err = rows.Scan(&stateAbbr); err != nil {
+			return nil, err
+		}
+		result = append(result, stateAbbr)
+	}
+	return result, rows.Err()
+}
+
+func (self *postgresStore) Districts(ctx context.Context, congress int) ([]District, error) {
+	sql := `SELECT state, district FROM house_district
+	WHERE congress_nbr = $1 ORDER BY state, district`
+	rows, err := self.db.QueryContext(ctx, sql, congress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []District
+	for rows.Next() {
+		var d District
+		if err = rows.Scan(&d.State, &d.Nbr); err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// queryIrregularities finds the irregular states with one query.  froms
+// gives, for each of Irregularities, the relation (with state and
+// congress_nbr columns) that lists the states that are irregular in that way.
+func queryIrregularities(ctx context.Context, db *sql.DB, froms []string,
+	congress int) (map[string][]string, error) {
+
+	var parts []string
+	for i, from := range froms {
+		parts = append(parts, fmt.Sprintf(
+			"SELECT state, %v AS idx FROM %v WHERE congress_nbr = $1", i, from))
+	}
+	sql := strings.Join(parts, " UNION ALL ") + " ORDER BY state, idx"
+	rows, err := db.QueryContext(ctx, sql, congress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string][]string)
+	for rows.Next() {
+		var state string
+		var idx int
+		if err = rows.Scan(&state, &idx); err != nil {
+			return nil, err
+		}
+		result[state] = append(result[state], Irregularities[idx])
+	}
+	return result, rows.Err()
+}
+
+func (self *postgresStore) Irregularities(ctx context.Context,
+	congress int) (map[string][]string, error) {
+
+	return queryIrregularities(ctx, self.db, Irregularities, congress)
+}
+
+// postgresCond returns an SQL condition on the house_district table (aliased
+// "dist") for the given scope.
+func (self Scope) postgresCond() (string, []interface{}) {
+	cond := "dist.congress_nbr = $1"
+	args := []interface{}{self.Congress}
+	if len(self.State) > 0 {
+		args = append(args, self.State)
+		cond += fmt.Sprintf(" AND dist.state = $%v", len(args))
+	}
+	if self.District != nil {
+		args = append(args, *self.District)
+		cond += fmt.Sprintf(" AND dist.district = $%v", len(args))
+	}
+	return cond, args
+}
+
+func (self *postgresStore) ForEachFact(ctx context.Context, scope Scope,
+	f func(Fact) error) error {
+
+	cond, args := scope.postgresCond()
+
+	/* ord puts turnout before populations */
+	sql := `SELECT dist.state, dist.district, 0 AS ord, 'turnout' AS type,
+		turnout.num_votes AS value, NULL::INTEGER AS margin_of_error, source.name
+	FROM house_district AS dist
+	JOIN house_district_turnout AS turnout ON (turnout.house_district_id = dist.id)
+	JOIN source ON (turnout.source_id = source.id)
+	WHERE ` + cond + `
+	UNION ALL
+	SELECT dist.state, dist.district, 1 AS ord, pop.type, pop.value,
+		pop.margin_of_error, source.name
+	FROM house_district AS dist
+	JOIN house_district_pop AS pop ON (pop.house_district_id = dist.id)
+	JOIN source ON (pop.source_id = source.id)
+	WHERE ` + cond + `
+	ORDER BY state, district, ord`
+	rows, err := self.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fact Fact
+		var ord int
+		err = rows.Scan(&fact.District.State, &fact.District.Nbr, &ord,
+			&fact.Type, &fact.Value, &fact.MarginOfError, &fact.Source)
+		if err != nil {
+			return err
+		}
+		if err = f(fact); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// queryStatePops runs a query for states and their populations and sources.
+func (self *postgresStore) queryStatePops(ctx context.Context, sql string,
+	args ...interface{}) (map[string]StatePop, error) {
+
+	rows, err := self.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]StatePop)
+	for rows.Next() {
+		var state string
+		var pop StatePop
+		if err = rows.Scan(&state, &pop.Value, &pop.Source); err != nil {
+			return nil, err
+		}
+		result[state] = pop
+	}
+	return result, rows.Err()
+}
+
+func (self *postgresStore) StatePops(ctx context.Context,
+	congress int) (map[string]StatePop, error) {
+
+	sql := `SELECT pop.state, pop.apportionment_pop, source.name
+	FROM congress JOIN census_state_pop AS pop
+	ON (congress.census_year = pop.census_year)
+	JOIN source ON (pop.source_id = source.id)
+	WHERE congress.nbr = $1 AND pop.apportionment_pop IS NOT NULL`
+	return self.queryStatePops(ctx, sql, congress)
+}
+
+func (self *postgresStore) CensusStatePops(ctx context.Context,
+	censusYear int) (map[string]StatePop, error) {
+
+	sql := `SELECT pop.state, pop.apportionment_pop, source.name
+	FROM census_state_pop AS pop
+	JOIN source ON (pop.source_id = source.id)
+	WHERE pop.census_year = $1 AND pop.apportionment_pop IS NOT NULL`
+	return self.queryStatePops(ctx, sql, censusYear)
+}
+
+func (self *postgresStore) StatePopProjections(ctx context.Context) ([]StatePopProjection, error) {
+	sql := `SELECT proj.year, proj.state, proj.scenario, proj.pop, source.name
+	FROM state_pop_projection AS proj
+	JOIN source ON (proj.source_id = source.id)
+	ORDER BY proj.year, proj.scenario, proj.state`
+	rows, err := self.db.QueryContext(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []StatePopProjection
+	for rows.Next() {
+		var proj StatePopProjection
+		err = rows.Scan(&proj.Year, &proj.State, &proj.Scenario, &proj.Value, &proj.Source)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, proj)
+	}
+	return result, rows.Err()
+}
+
+func (self *postgresStore) RepTerms(ctx context.Context, scope Scope) ([]RepTerm, error) {
+	sql := `SELECT term.bioguide_id, leg.first_name, leg.middle_name,
+		leg.last_name, term.party, term.state, dist.district, term.start_date,
+		term.end_date
+	FROM representative_term AS term
+	LEFT OUTER JOIN legislator AS leg ON (term.bioguide_id = leg.bioguide_id)
+	LEFT OUTER JOIN house_district AS dist ON (term.house_district_id = dist.id)
+	WHERE term.congress_nbr = $1`
+	args := []interface{}{scope.Congress}
+	if len(scope.State) > 0 {
+		args = append(args, scope.State)
+		sql += fmt.Sprintf(" AND term.state = $%v", len(args))
+	}
+	if scope.District != nil {
+		args = append(args, *scope.District)
+		sql += fmt.Sprintf(" AND dist.district = $%v", len(args))
+	}
+	sql += " ORDER BY term.state, dist.district, term.start_date, term.bioguide_id"
+	rows, err := self.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []RepTerm
+	for rows.Next() {
+		var term RepTerm
+		err = rows.Scan(&term.BioguideID, &term.FirstName, &term.MiddleName,
+			&term.LastName, &term.Party, &term.State, &term.District,
+			&term.StartDate, &term.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, term)
+	}
+	return result, rows.Err()
+}
+
+func (self *postgresStore) ForEachDistrictShape(ctx context.Context, scope Scope,
+	f func(DistrictShape) error) error {
+
+	cond, args := scope.postgresCond()
+	sql := `SELECT dist.state, dist.district, shape.shape_id, shape.geometry
+	FROM house_district AS dist
+	JOIN house_district_shape AS shape ON (shape.house_district_id = dist.id)
+	WHERE ` + cond + `
+	ORDER BY dist.state, dist.district`
+	rows, err := self.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shape DistrictShape
+		err = rows.Scan(&shape.District.State, &shape.District.Nbr, &shape.ShapeID,
+			&shape.Geometry)
+		if err != nil {
+			return err
+		}
+		if err = f(shape); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (self *postgresStore) StateShapes(ctx context.Context,
+	congress int) ([]StateShape, error) {
+
+	rows, err := self.db.QueryContext(ctx,
+		"SELECT state, geometry FROM state_shape WHERE congress_nbr = $1 ORDER BY state",
+		congress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []StateShape
+	for rows.Next() {
+		var shape StateShape
+		if err = rows.Scan(&shape.State, &shape.Geometry); err != nil {
+			return nil, err
+		}
+		result = append(result, shape)
+	}
+	return result, rows.Err()
+}
+
+func (self *postgresStore) DataVersion(ctx context.Context) (DataVersion, error) {
+	var version DataVersion
+	err := self.db.QueryRowContext(ctx,
+		"SELECT version, updated_at FROM data_version WHERE id = 1").Scan(
+		&version.Nbr, &version.UpdatedAt)
+	if err == sql.ErrNoRows {
+		/* loaddata hasn't finished a load yet */
+		return version, nil
+	}
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "undefined_table" {
+		/* The DB predates data_version and schema.sql hasn't been rerun */
+		return version, nil
+	}
+	return version, err
+}
+
+// gPostgresRelations are the tables and views that the API needs.
+var gPostgresRelations = []string{
+	"source",
+	"congress",
+	"house_district",
+	"house_district_pop",
+	"house_district_turnout",
+	"house_district_shape",
+	"state_shape",
+	"census_state_pop",
+	"state_pop_projection",
+	"legislator",
+	"representative_term",
+	"data_version",
+	"state_with_atlarge_and_nonatlarge_districts",
+	"state_with_overlapping_terms",
+	"state_with_unknown_district",
+	"irregular_state",
+}
+
+func (self *postgresStore) Health(ctx context.Context) (Health, error) {
+	var health Health
+	if err := self.db.PingContext(ctx); err != nil {
+		return health, err
+	}
+
+	// check relations
+	for _, name := range gPostgresRelations {
+		var exists bool
+		err := self.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL",
+			name).Scan(&exists)
+		if err != nil {
+			return health, err
+		}
+		if !exists {
+			health.MissingRelations = append(health.MissingRelations, name)
+		}
+	}
+	if len(health.MissingRelations) > 0 {
+		return health, nil
+	}
+
+	// get sources
+	rows, err := self.db.QueryContext(ctx, "SELECT name, loaded_at FROM source ORDER BY name")
+	if err != nil {
+		return health, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var load SourceLoad
+		var loadedAt sql.NullTime
+		if err := rows.Scan(&load.Source, &loadedAt); err != nil {
+			return health, err
+		}
+		load.LoadedAt = loadedAt.Time
+		health.Sources = append(health.Sources, load)
+	}
+	return health, rows.Err()
+}
@@ -0,0 +1,297 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+/*
+The SQLite DB is the one built by map-data's housedb package.  It has no
+congress or house_district tables, so congresses and districts are derived
+from the representative terms and turnout records.  It also has no
+population data or shapes.
+*/
+
+const gFirstCongressStartYear = 1789
+
+type turnoutTable struct {
+	name   string
+	source string
+}
+
+var gSqliteTurnoutTables = []turnoutTable{
+	{"tufts_district_turnout", "Lampi Collection of American Electoral Returns, 1787–1825. American Antiquarian Society, 2007."},
+	{"harvard_district_turnout", "MIT Election Data and Science Lab, 2017, \"U.S. House 1976–2018\", " +
+		"https://doi.org/10.7910/DVN/IG0UN2, Harvard Dataverse, V5, UNF:6:f4KhIVuYz/VinGbLYysWJg=="},
+}
+
+/*
+In the SQLite DB, the state_with_unknown_district view also matches at-large
+terms (whose district_nbr is NULL too), so we use our own query instead.
+*/
+var gSqliteIrregularityFroms = []string{
+	"state_with_atlarge_and_nonatlarge_districts",
+	"state_with_overlapping_terms",
+	`(SELECT DISTINCT state, congress_nbr FROM representative_term
+		WHERE district_nbr IS NULL AND at_large IS NULL)`,
+}
+
+type sqliteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// OpenSQLite returns a store backed by the SQLite DB at the given path, which
+// must have been built by map-data's housedb package.  The DB is opened
+// read-only.
+func OpenSQLite(path string, pool PoolOptions) (Store, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%v?mode=ro", path))
+	if err != nil {
+		return nil, err
+	}
+	pool.apply(db)
+	return &sqliteStore{db: db, path: path}, nil
+}
+
+func (self *sqliteStore) Close() error {
+	return self.db.Close()
+}
+
+func (self *sqliteStore) PoolStats() sql.DBStats {
+	return self.db.Stats()
+}
+
+func (self *sqliteStore) Congresses(ctx context.Context) ([]Congress, error) {
+	sql := `SELECT congress_nbr FROM representative_term
+	UNION SELECT congress_nbr FROM tufts_district_turnout
+	UNION SELECT congress_nbr FROM harvard_district_turnout
+	ORDER BY congress_nbr`
+	rows, err := self.db.QueryContext(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Congress
+	for rows.Next() {
+		var con Congress
+		if err = rows.Scan(&con.Nbr); err != nil {
+			return nil, err
+		}
+		con.StartYear = gFirstCongressStartYear + 2*(con.Nbr-1)
+		result = append(result, con)
+	}
+	return result, rows.Err()
+}
+
+func (self *sqliteStore) States(ctx context.Context, congress int) ([]string, error) {
+	districts, err := self.Districts(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, d := range districts {
+		if len(result) == 0 || result[len(result)-1] != d.State {
+			result = append(result, d.State)
+		}
+	}
+	return result, nil
+}
+
+func (self *sqliteStore) Districts(ctx context.Context, congress int) ([]District, error) {
+	sql := `SELECT state, district_nbr FROM representative_term
+		WHERE congress_nbr = $1 AND district_nbr IS NOT NULL
+	UNION SELECT state, 0 FROM representative_term
+		WHERE congress_nbr = $1 AND at_large IS TRUE
+	UNION SELECT state, district_nbr FROM tufts_district_turnout
+		WHERE congress_nbr = $1
+	UNION SELECT state, district_nbr FROM harvard_district_turnout
+		WHERE congress_nbr = $1
+	ORDER BY 1, 2`
+	rows, err := self.db.QueryContext(ctx, sql, congress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []District
+	for rows.Next() {
+		var d District
+		if err = rows.Scan(&d.State, &d.Nbr); err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+func (self *sqliteStore) Irregularities(ctx context.Context,
+	congress int) (map[string][]string, error) {
+
+	return queryIrregularities(ctx, self.db, gSqliteIrregularityFroms, congress)
+}
+
+// sqliteCond returns an SQL condition on a table with congress_nbr, state,
+// and distExpr columns for the given scope.
+func (self Scope) sqliteCond(distExpr string) (string, []interface{}) {
+	cond := "congress_nbr = $1"
+	args := []interface{}{self.Congress}
+	if len(self.State) > 0 {
+		args = append(args, self.State)
+		cond += fmt.Sprintf(" AND state = $%v", len(args))
+	}
+	if self.District != nil {
+		args = append(args, *self.District)
+		cond += fmt.Sprintf(" AND %v = $%v", distExpr, len(args))
+	}
+	return cond, args
+}
+
+func (self *sqliteStore) ForEachFact(ctx context.Context, scope Scope,
+	f func(Fact) error) error {
+
+	cond, args := scope.sqliteCond("district_nbr")
+	var parts []string
+	for i, table := range gSqliteTurnoutTables {
+		/* Like the district_turnout view, ignore implausibly small values */
+		parts = append(parts, fmt.Sprintf(`SELECT state, district_nbr, %v AS ord,
+			turnout FROM %v WHERE turnout > 10 AND %v`, i, table.name, cond))
+	}
+	sql := strings.Join(parts, " UNION ALL ") + " ORDER BY state, district_nbr, ord"
+	rows, err := self.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		fact := Fact{Type: "turnout"}
+		var ord int
+		err = rows.Scan(&fact.District.State, &fact.District.Nbr, &ord, &fact.Value)
+		if err != nil {
+			return err
+		}
+		fact.Source = gSqliteTurnoutTables[ord].source
+		if err = f(fact); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (self *sqliteStore) StatePops(ctx context.Context,
+	congress int) (map[string]StatePop, error) {
+
+	return make(map[string]StatePop), nil
+}
+
+func (self *sqliteStore) CensusStatePops(ctx context.Context,
+	censusYear int) (map[string]StatePop, error) {
+
+	return make(map[string]StatePop), nil
+}
+
+func (self *sqliteStore) StatePopProjections(ctx context.Context) ([]StatePopProjection, error) {
+	return nil, nil
+}
+
+func (self *sqliteStore) RepTerms(ctx context.Context, scope Scope) ([]RepTerm, error) {
+	const distExpr = "(CASE WHEN at_large IS TRUE THEN 0 ELSE district_nbr END)"
+	cond, args := scope.sqliteCond(distExpr)
+	sql := fmt.Sprintf(`SELECT bioguide, first_name, middle_name, last_name,
+		state, %v, start_date, end_date
+	FROM representative_term
+	WHERE %v
+	ORDER BY state, 6, start_date, bioguide`, distExpr, cond)
+	rows, err := self.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []RepTerm
+	for rows.Next() {
+		var term RepTerm
+		var firstName, lastName string
+		err = rows.Scan(&term.BioguideID, &firstName, &term.MiddleName,
+			&lastName, &term.State, &term.District, &term.StartDate,
+			&term.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		term.FirstName = &firstName
+		term.LastName = &lastName
+		result = append(result, term)
+	}
+	return result, rows.Err()
+}
+
+func (self *sqliteStore) ForEachDistrictShape(ctx context.Context, scope Scope,
+	f func(DistrictShape) error) error {
+
+	/* The SQLite DB has no shapes */
+	return nil
+}
+
+func (self *sqliteStore) StateShapes(ctx context.Context,
+	congress int) ([]StateShape, error) {
+
+	return nil, nil
+}
+
+/*
+The SQLite DB is rebuilt rather than updated, so we use its modification time
+as its version.
+*/
+func (self *sqliteStore) DataVersion(ctx context.Context) (DataVersion, error) {
+	info, err := os.Stat(self.path)
+	if err != nil {
+		return DataVersion{}, err
+	}
+	return DataVersion{Nbr: info.ModTime().UnixNano(), UpdatedAt: info.ModTime()}, nil
+}
+
+// gSqliteRelations are the tables and views that the API needs.
+var gSqliteRelations = []string{
+	"representative_term",
+	"tufts_district_turnout",
+	"harvard_district_turnout",
+	"state_with_atlarge_and_nonatlarge_districts",
+	"state_with_overlapping_terms",
+}
+
+func (self *sqliteStore) Health(ctx context.Context) (Health, error) {
+	var health Health
+	if err := self.db.PingContext(ctx); err != nil {
+		return health, err
+	}
+
+	// check relations
+	for _, name := range gSqliteRelations {
+		var count int
+		err := self.db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM sqlite_master WHERE name = ?", name).Scan(&count)
+		if err != nil {
+			return health, err
+		}
+		if count == 0 {
+			health.MissingRelations = append(health.MissingRelations, name)
+		}
+	}
+
+	/* All the sources were loaded when the DB was built */
+	info, err := os.Stat(self.path)
+	if err != nil {
+		return health, err
+	}
+	for _, table := range gSqliteTurnoutTables {
+		health.Sources = append(health.Sources,
+			SourceLoad{Source: table.source, LoadedAt: info.ModTime()})
+	}
+	return health, nil
+}
@@ -0,0 +1,226 @@
+// Package store provides access to the data that the API serves.
+//
+// The data can come from the Postgres DB that loaddata fills, from the
+// SQLite DB that the map-data pipeline builds, or from memory (for tests and
+// demos).
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Congress struct {
+	Nbr       int
+	StartYear int
+}
+
+// District identifies a House district.  Nbr is 0 for at-large districts.
+type District struct {
+	State string
+	Nbr   int
+}
+
+// Fact is a number known about a district, such as its turnout or one of its
+// populations.
+type Fact struct {
+	District District
+
+	// Type is "turnout" or one of the population types ("all", "adults",
+	// "citizens", or "cvap").
+	Type string
+
+	Value         int
+	MarginOfError *int /* nil for exact values (e.g., turnout) */
+	Source        string
+}
+
+// StatePop is the apportionment population of a state.
+type StatePop struct {
+	Value  int
+	Source string
+}
+
+// StatePopProjection is a projection of a state's population at a future
+// census.
+type StatePopProjection struct {
+	Year     int /* of the census */
+	State    string
+	Scenario string /* "low", "middle", or "high" */
+	Value    int
+	Source   string
+}
+
+// RepTerm is the term of a member of the House.
+type RepTerm struct {
+	BioguideID string
+	FirstName  *string
+	MiddleName *string
+	LastName   *string
+	Party      *string
+	State      string
+	District   *int /* nil == unknown */
+	StartDate  time.Time
+	EndDate    time.Time
+}
+
+// DistrictShape is (part of) the shape of a district.
+type DistrictShape struct {
+	District District
+	ShapeID  string /* ID of the district in the tilesets */
+	Geometry []byte /* GeoJSON geometry */
+}
+
+// StateShape is (part of) the shape of a state.
+type StateShape struct {
+	State    string
+	Geometry []byte /* GeoJSON geometry */
+}
+
+// DataVersion identifies a version of the data.  It changes whenever the
+// data does.
+type DataVersion struct {
+	Nbr       int64
+	UpdatedAt time.Time /* zero if unknown */
+}
+
+// SourceLoad says when the data from a source was last loaded.
+type SourceLoad struct {
+	Source   string
+	LoadedAt time.Time /* zero if unknown */
+}
+
+// Health describes whether a store can serve its data.
+type Health struct {
+	// MissingRelations are the required tables and views that don't exist.
+	MissingRelations []string
+
+	Sources []SourceLoad
+}
+
+// PoolOptions configures a store's pool of DB connections.  Zero values mean
+// the database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+func (self PoolOptions) apply(db *sql.DB) {
+	if self.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(self.MaxOpenConns)
+	}
+	if self.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(self.MaxIdleConns)
+	}
+	if self.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(self.ConnMaxLifetime)
+	}
+}
+
+// Pooled is implemented by stores that have a pool of DB connections.
+type Pooled interface {
+	PoolStats() sql.DBStats
+}
+
+// Scope narrows a query to one congress and, optionally, one state and one
+// district.
+type Scope struct {
+	Congress int
+	State    string /* "" == all states */
+	District *int   /* nil == all districts */
+}
+
+// Irregularities are the names of the ways in which a state's districts are
+// irregular, in the order that they should be reported.
+var Irregularities = []string{
+	"state_with_atlarge_and_nonatlarge_districts",
+	"state_with_overlapping_terms",
+	"state_with_unknown_district",
+}
+
+// Store is a source of data about congresses and their districts.
+//
+// Each method loads everything it returns in a constant number of queries.
+type Store interface {
+	Congresses(ctx context.Context) ([]Congress, error)
+
+	// States returns the states that had districts in the given congress.
+	States(ctx context.Context, congress int) ([]string, error)
+
+	Districts(ctx context.Context, congress int) ([]District, error)
+
+	// Irregularities maps each irregular state in the given congress to the
+	// ways it is irregular (see Irregularities).
+	Irregularities(ctx context.Context, congress int) (map[string][]string, error)
+
+	// ForEachFact calls f for each fact about the districts in the given
+	// scope, in order of state and district, without loading them all into
+	// memory.  It stops at the first error that f returns, and returns it.
+	ForEachFact(ctx context.Context, scope Scope, f func(Fact) error) error
+
+	// StatePops maps each state to its population according to the census by
+	// which the given congress was apportioned.
+	StatePops(ctx context.Context, congress int) (map[string]StatePop, error)
+
+	// CensusStatePops maps each state to its population according to the
+	// given census.
+	CensusStatePops(ctx context.Context, censusYear int) (map[string]StatePop, error)
+
+	// StatePopProjections returns all the projections of the states'
+	// populations, in order of year, scenario, and state.
+	StatePopProjections(ctx context.Context) ([]StatePopProjection, error)
+
+	// RepTerms returns the terms of the representatives in the given scope,
+	// in order of state, district, and start date.
+	RepTerms(ctx context.Context, scope Scope) ([]RepTerm, error)
+
+	// ForEachDistrictShape calls f for each shape of the districts in the
+	// given scope, in order of state and district.  It stops at the first
+	// error that f returns, and returns it.
+	ForEachDistrictShape(ctx context.Context, scope Scope, f func(DistrictShape) error) error
+
+	// StateShapes returns the shapes of the states in the given congress.
+	StateShapes(ctx context.Context, congress int) ([]StateShape, error)
+
+	// DataVersion returns the current version of the data.
+	DataVersion(ctx context.Context) (DataVersion, error)
+
+	// Health checks that the DB can be reached and has the tables and views
+	// that we need.  It returns an error if the DB can't be reached.
+	Health(ctx context.Context) (Health, error)
+
+	Close() error
+}
+
+// Facts returns the facts about the districts in the given scope, in order
+// of state and district.
+func Facts(ctx context.Context, s Store, scope Scope) ([]Fact, error) {
+	var result []Fact
+	err := s.ForEachFact(ctx, scope, func(f Fact) error {
+		result = append(result, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// HasDistrict returns whether the given district existed in the given
+// congress.
+func HasDistrict(ctx context.Context, s Store, congress int,
+	district District) (bool, error) {
+
+	districts, err := s.Districts(ctx, congress)
+	if err != nil {
+		return false, err
+	}
+	for _, d := range districts {
+		if d == district {
+			return true, nil
+		}
+	}
+	return false, nil
+}
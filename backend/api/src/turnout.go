@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+
+	"expandourhouse.com/api/store"
+)
+
+/*
+A district's turnout may come from several sources (e.g., both the Tufts
+and the MIT data cover a congress).  We return every source's value, plus a
+preferred value: the one from the source that comes first in the configured
+priority.  If the sources' values differ by more than the configured
+fraction of the largest one, the turnout is flagged as disputed, so that
+users can see the disagreement.
+*/
+
+// turnoutPolicy says how to reconcile turnouts from different sources.
+type turnoutPolicy struct {
+	// Priority lists (parts of) source names, most preferred first.
+	// Matching is case-insensitive.  Sources that match none come last.
+	Priority []string
+
+	// Disagreement is the fraction of the largest value by which the values
+	// may differ before they're flagged.
+	Disagreement float64
+}
+
+var gTurnoutPolicy = turnoutPolicy{
+	Priority:     gDefaultTurnoutPriority,
+	Disagreement: gDefaultTurnoutDisagreement,
+}
+
+var gDefaultTurnoutPriority = []string{"MIT Election Data", "Lampi Collection"}
+
+const gDefaultTurnoutDisagreement = 0.05
+
+type turnoutFact struct {
+	fact /* the preferred source's */
+
+	Sources      []*fact `json:"sources"` /* in order of priority */
+	Disagreement bool    `json:"disagreement"`
+}
+
+// rank returns the position of the given source in the priority.
+func (self *turnoutPolicy) rank(source string) int {
+	source = strings.ToLower(source)
+	for i, part := range self.Priority {
+		if strings.Contains(source, strings.ToLower(part)) {
+			return i
+		}
+	}
+	return len(self.Priority)
+}
+
+// add adds a source's value to the given turnout, and then reconciles the
+// sources.
+func (self *turnoutPolicy) add(turnout *turnoutFact, value fact) {
+	/* Insert after the sources with the same or higher priority */
+	rank := self.rank(value.Source)
+	i := 0
+	for i < len(turnout.Sources) && self.rank(turnout.Sources[i].Source) <= rank {
+		i++
+	}
+	turnout.Sources = append(turnout.Sources, nil)
+	copy(turnout.Sources[i+1:], turnout.Sources[i:])
+	turnout.Sources[i] = &value
+
+	// reconcile
+	turnout.fact = *turnout.Sources[0]
+	min, max := turnout.Value, turnout.Value
+	for _, source := range turnout.Sources {
+		if source.Value < min {
+			min = source.Value
+		}
+		if source.Value > max {
+			max = source.Value
+		}
+	}
+	turnout.Disagreement = float64(max-min) > self.Disagreement*float64(max)
+}
+
+// addTurnout adds the given turnout fact to the given district's facts.
+func addTurnout(distFacts districtFacts, f store.Fact) {
+	turnout, ok := distFacts[f.Type].(*turnoutFact)
+	if !ok {
+		turnout = &turnoutFact{}
+		distFacts[f.Type] = turnout
+	}
+	gTurnoutPolicy.add(turnout, fact{Value: f.Value, Source: f.Source})
+}
+
+// turnoutOf returns the (preferred) turnout in the given district, if known.
+func turnoutOf(distFacts districtFacts) (int, bool) {
+	turnout, ok := distFacts["turnout"].(*turnoutFact)
+	if !ok {
+		return 0, false
+	}
+	return turnout.Value, true
+}
@@ -2,176 +2,199 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-)
-
-type stateIrregularity struct {
-	dbTable string
-}
 
-func (irreg *stateIrregularity) hasState(ctx context.Context, stateAbbr string,
-	congress int) (bool, error) {
+	"expandourhouse.com/api/store"
+)
 
-	sql := fmt.Sprintf("SELECT COUNT(*) FROM %v WHERE state = $1 AND congress_nbr = $2",
-		irreg.dbTable)
-	rows, err := gDb.QueryContext(ctx, sql, stateAbbr, congress)
+// getDistricts returns the districts of the given congress, grouped by state.
+func getDistricts(ctx context.Context, congress int) (map[string][]store.District, error) {
+	districts, err := gStore.Districts(ctx, congress)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	defer rows.Close()
 
-	var count int
-	rows.Next()
-	if err = rows.Scan(&count); err != nil {
-		return false, err
+	result := make(map[string][]store.District)
+	for _, d := range districts {
+		result[d.State] = append(result[d.State], d)
 	}
-	return count > 0, nil
+	return result, nil
 }
 
-var gStateIrregularities = []stateIrregularity{
-	stateIrregularity{dbTable: "state_with_atlarge_and_nonatlarge_districts"},
-	stateIrregularity{dbTable: "state_with_overlapping_terms"},
-	stateIrregularity{dbTable: "state_with_unknown_district"},
+// addFact adds the given fact to the given district's facts.  Turnouts from
+// different sources are reconciled.
+func addFact(distFacts districtFacts, f store.Fact) {
+	if f.Type == "turnout" {
+		addTurnout(distFacts, f)
+	} else if f.MarginOfError == nil {
+		distFacts[f.Type] = &fact{Value: f.Value, Source: f.Source}
+	} else {
+		distFacts[f.Type] = &factWithMoe{
+			fact:          fact{Value: f.Value, Source: f.Source},
+			MarginOfError: *f.MarginOfError,
+		}
+	}
 }
 
-func getStateIrregularities(ctx context.Context, stateAbbr string,
-	congress int) ([]string, error) {
+// factOf returns the fact of the given type (e.g., "turnout" or "cvap") in
+// the given district's facts, or nil if there is none.  For turnout, it's
+// the preferred source's.
+func factOf(distFacts districtFacts, factType string) *fact {
+	switch f := distFacts[factType].(type) {
+	case *fact:
+		return f
+	case *factWithMoe:
+		return &f.fact
+	case *turnoutFact:
+		return &f.fact
+	default:
+		return nil
+	}
+}
 
-	var irregularities []string
-	for _, irregularity := range gStateIrregularities {
-		hasIrreg, err := irregularity.hasState(ctx, stateAbbr, congress)
-		if err != nil {
-			return nil, err
-		}
-		if hasIrreg {
-			irregularities = append(irregularities, irregularity.dbTable)
+// getDistrictFacts returns the facts for the districts in the given scope.
+func getDistrictFacts(ctx context.Context,
+	scope store.Scope) (map[store.District]districtFacts, error) {
+
+	result := make(map[store.District]districtFacts)
+	err := gStore.ForEachFact(ctx, scope, func(f store.Fact) error {
+		distFacts, ok := result[f.District]
+		if !ok {
+			distFacts = make(districtFacts)
+			result[f.District] = distFacts
 		}
+		addFact(distFacts, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return irregularities, nil
+	return result, nil
 }
 
-func getStates(ctx context.Context, congress int) ([]string, error) {
-	sql := `SELECT DISTINCT state FROM house_district
-	WHERE congress_nbr = $1`
-	rows, err := gDb.QueryContext(ctx, sql, congress)
+// getStates returns info about each state in the given congress.  Irregular
+// states' districts are left out, but not their aggregates.
+func getStates(ctx context.Context, congress int) (map[string]*stateInfo, error) {
+	// get all states for this congress
+	states, err := gStore.States(ctx, congress)
 	if err != nil {
 		return nil, err
 	}
 
-	var result []string
-	for rows.Next() {
-		var stateAbbr string
-		if err = rows.Scan(&stateAbbr); err != nil {
-			return nil, err
-		}
-		result = append(result, stateAbbr)
+	// get all districts for this congress
+	allDistricts, err := getDistricts(ctx, congress)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
-}
-
-type districtInfo struct {
-	rowID int
-	nbr   int
-}
-
-func getDistricts(ctx context.Context, congress int) (map[string][]*districtInfo, error) {
-	sql := `SELECT id, district, state FROM house_district WHERE congress_nbr = $1`
-	rows, err := gDb.QueryContext(ctx, sql, congress)
+	// get irregularities and facts for the whole congress
+	irregularities, err := gStore.Irregularities(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	allFacts, err := getDistrictFacts(ctx, store.Scope{Congress: congress})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	result := make(map[string][]*districtInfo)
-	for rows.Next() {
-		var di districtInfo
-		var state string
-		if err = rows.Scan(&di.rowID, &di.nbr, &state); err != nil {
-			return nil, err
+	// assemble info for each state
+	result := make(map[string]*stateInfo)
+	for _, stateAbbr := range states {
+		state := stateInfo{
+			IrregularHow: irregularities[stateAbbr],
+			Districts:    nil,
+			Aggregates:   aggregateState(allDistricts[stateAbbr], allFacts),
+		}
+		result[stateAbbr] = &state
+		if len(state.IrregularHow) > 0 {
+			continue
 		}
 
-		_, ok := result[state]
-		if !ok {
-			result[state] = nil
+		state.Districts = make(map[string]districtFacts)
+		for _, d := range allDistricts[stateAbbr] {
+			distFacts, ok := allFacts[d]
+			if !ok {
+				distFacts = make(districtFacts)
+			}
+			state.Districts[fmt.Sprintf("%v", d.Nbr)] = distFacts
 		}
-		result[state] = append(result[state], &di)
 	}
-
 	return result, nil
 }
 
-func getDistrictFacts(ctx context.Context, districtID int) (districtFacts, error) {
-	facts := make(districtFacts)
-	var rows *sql.Rows
-	var err error
-	var sql string
-
-	// get turnout
-	sql = `SELECT turnout.num_votes, source.name
-	FROM house_district_turnout AS turnout JOIN source
-	ON (turnout.source_id = source.id)
-	WHERE house_district_id = $1`
-	rows, err = gDb.QueryContext(ctx, sql, districtID)
+// getActualSeats returns the number of districts that each state had in
+// the given congress.
+func getActualSeats(ctx context.Context, congress int) (map[string]int, error) {
+	districts, err := gStore.Districts(ctx, congress)
 	if err != nil {
-		goto done
+		return nil, err
 	}
-	if rows.Next() {
-		var f fact
-		if err = rows.Scan(&f.Value, &f.Source); err != nil {
-			goto done
-		}
-		facts["turnout"] = &f
+
+	result := make(map[string]int)
+	for _, d := range districts {
+		result[d.State]++
 	}
-	rows.Close()
+	return result, nil
+}
 
-	// get populations
-	sql = `SELECT pop.type, pop.value, pop.margin_of_error, source.name
-	FROM house_district_pop AS pop JOIN source
-	ON (pop.source_id = source.id)
-	WHERE house_district_id = $1`
-	rows, err = gDb.QueryContext(ctx, sql, districtID)
+// congressExists returns whether the given congress is in the DB.
+func congressExists(ctx context.Context, congress int) (bool, error) {
+	cons, err := gStore.Congresses(ctx)
 	if err != nil {
-		goto done
+		return false, err
 	}
-	for rows.Next() {
-		var f factWithMoe
-		var fType string
-		if err = rows.Scan(&fType, &f.Value, &f.MarginOfError, &f.Source); err != nil {
-			goto done
+	for _, con := range cons {
+		if con.Nbr == congress {
+			return true, nil
 		}
-		facts[fType] = &f
 	}
-	rows.Close()
+	return false, nil
+}
 
-done:
-	if rows != nil {
-		rows.Close()
-	}
+// requireCongress returns a not-found error if the given congress isn't in
+// the DB.
+func requireCongress(ctx context.Context, congress int) error {
+	exists, err := congressExists(ctx, congress)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if !exists {
+		return errNotFound("No such congress: %v", congress)
 	}
-	return facts, nil
+	return nil
 }
 
-func getDistrictID(ctx context.Context, congress int, state string,
-	district int) (*int, error) {
-
-	sql := `SELECT id FROM house_district
-	WHERE congress_nbr = $1 AND state = $2 AND district = $3`
-	rows, err := gDb.QueryContext(ctx, sql, congress, state, district)
+// requireState returns a not-found error if the given state isn't in the
+// given congress.
+func requireState(ctx context.Context, congress int, state string) error {
+	if err := requireCongress(ctx, congress); err != nil {
+		return err
+	}
+	states, err := gStore.States(ctx, congress)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	for _, s := range states {
+		if s == state {
+			return nil
+		}
+	}
+	return errNotFound("No such state in congress %v: %v", congress, state)
+}
 
-	if !rows.Next() {
-		return nil, nil
+// requireDistrict returns a not-found error if the given district isn't in
+// the given congress.
+func requireDistrict(ctx context.Context, congress int, district store.District) error {
+	if err := requireState(ctx, congress, district.State); err != nil {
+		return err
 	}
-	var id int
-	if err = rows.Scan(&id); err != nil {
-		return nil, err
+	exists, err := store.HasDistrict(ctx, gStore, congress, district)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errNotFound("No such district in congress %v: %v-%v", congress,
+			district.State, district.Nbr)
 	}
-	return &id, nil
+	return nil
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"expandourhouse.com/api/store"
+	"github.com/gorilla/mux"
+)
+
+/*
+The benchmarks run against a database given by one of these environment
+variables.  They are skipped if neither is set.
+*/
+const gTestPostgresEnv = "EOH_TEST_POSTGRES" // Postgres connection string
+const gTestSQLiteEnv = "EOH_TEST_SQLITE"     // path to map-data's SQLite DB
+const gTestCongressEnv = "EOH_TEST_CONGRESS" // default: 115
+
+// useTestDb points gStore at the test database, and returns a function that
+// restores it.
+func useTestDb(b *testing.B) func() {
+	var db store.Store
+	var err error
+	if connStr := os.Getenv(gTestPostgresEnv); len(connStr) > 0 {
+		db, err = store.OpenPostgres(connStr, store.PoolOptions{})
+	} else if path := os.Getenv(gTestSQLiteEnv); len(path) > 0 {
+		db, err = store.OpenSQLite(path, store.PoolOptions{})
+	} else {
+		b.Skipf("Neither %v nor %v is set", gTestPostgresEnv, gTestSQLiteEnv)
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	oldStore := gStore
+	gStore = db
+	return func() {
+		gStore = oldStore
+		db.Close()
+	}
+}
+
+func testCongress() string {
+	if congress := os.Getenv(gTestCongressEnv); len(congress) > 0 {
+		return congress
+	}
+	return "115"
+}
+
+func BenchmarkGetStates(b *testing.B) {
+	defer useTestDb(b)()
+	congress := testCongress()
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/congresses/%v/states", congress), nil)
+	req = mux.SetURLVars(req, map[string]string{"congress": congress})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp := httptest.NewRecorder()
+		handleGetStates(resp, req)
+		if resp.Code != http.StatusOK {
+			b.Fatalf("Got status %v", resp.Code)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// Package censusPop loads the populations of the states as counted by the
+// decennial censuses.
+//
+// The data is read from the CSV files in the "census" subdirectory of the
+// data directory.  Each file begins with a header row; the columns may be in
+// any order:
+//
+//	year               Census year (required)
+//	state              USPS code or name of the state (required)
+//	resident           Resident population (required)
+//	apportionment      Official apportionment population
+//	apportioned        "false" for areas that were not apportioned seats,
+//	                   such as territories
+//	enslaved           Enslaved persons (1790-1860)
+//	indians_not_taxed  "Indians not taxed" (1790-1930)
+//	overseas           Federal employees overseas (and their dependents)
+//	                   allocated to the state
+//
+// If the apportionment column is empty, the apportionment population is
+// computed from the other columns according to the rules in force at the
+// time of the census.
+//
+// The Census Bureau's historical apportionment data (apportionment.csv, which
+// the Makefile downloads) can also be read as is: its "Name", "Year", and
+// "Resident Population" columns are taken as state, year, and resident, and
+// its rows for regions and the nation are skipped.  It has no overseas
+// counts, so its apportionment populations from 1970 on are slightly low.
+package censusPop
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/lib/apportionment"
+	"expandourhouse.com/loaddata/bulkInserter"
+	"expandourhouse.com/loaddata/utils"
+)
+
+type censusRec struct {
+	year             int
+	stateUsps        string
+	resident         int
+	apportionmentPop *int
+}
+
+// computeApportionmentPop applies the Constitution's rules for counting
+// people for apportionment.
+func computeApportionmentPop(year, resident, enslaved, indiansNotTaxed,
+	overseas int) int {
+
+	/*
+		"Indians not taxed" were excluded until the 1940 census, when the
+		Census Bureau found there were no longer any.
+	*/
+	pop := resident - indiansNotTaxed
+
+	/*
+		Three-fifths clause (Art. I, Sec. 2), until the 14th Amendment.
+	*/
+	if year <= 1860 {
+		pop = pop - enslaved + (enslaved*3)/5
+	}
+
+	/*
+		Overseas federal employees were counted in 1970 and from 1990 on.
+	*/
+	if year == 1970 || year >= 1990 {
+		pop += overseas
+	}
+
+	return pop
+}
+
+// gColAliases maps the names of the columns in the Census Bureau's files to
+// ours.
+var gColAliases = map[string]string{
+	"name":                "state",
+	"resident population": "resident",
+}
+
+type censusReader struct {
+	csvReader    *csv.Reader
+	colNameToIdx map[string]int
+}
+
+func newCensusReader(f *os.File) *censusReader {
+	r := &censusReader{csv.NewReader(f), nil}
+	r.csvReader.ReuseRecord = true
+	return r
+}
+
+func (self *censusReader) getVal(rec []string, col string) string {
+	idx, ok := self.colNameToIdx[col]
+	if !ok || idx >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[idx])
+}
+
+func (self *censusReader) getNbr(rec []string, col string) (int, error) {
+	val := strings.Replace(self.getVal(rec, col), ",", "", -1)
+	if len(val) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("Bad value for %v: %v", col, err)
+	}
+	return n, nil
+}
+
+func (self *censusReader) read() (*censusRec, error) {
+	var rec []string
+	var err error
+	var data censusRec
+	var enslaved, indiansNotTaxed, overseas int
+
+do:
+	rec, err = self.csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(rec) == 0 {
+		goto do
+	}
+
+	if self.colNameToIdx == nil {
+		// keep column names
+		self.colNameToIdx = make(map[string]int)
+		for idx, colName := range rec {
+			colName = strings.ToLower(strings.TrimSpace(colName))
+			if alias, ok := gColAliases[colName]; ok {
+				colName = alias
+			}
+			self.colNameToIdx[colName] = idx
+		}
+		for _, col := range []string{"year", "state", "resident"} {
+			if _, ok := self.colNameToIdx[col]; !ok {
+				return nil, fmt.Errorf("Missing column: %v", col)
+			}
+		}
+		goto do
+	}
+
+	// skip regions and the nation
+	geoType := self.getVal(rec, "geography type")
+	if len(geoType) > 0 && strings.ToLower(geoType) != "state" {
+		goto do
+	}
+
+	// get state
+	state := self.getVal(rec, "state")
+	if len(state) == 2 {
+		data.stateUsps = strings.ToUpper(state)
+	} else {
+		data.stateUsps, err = utils.GetUspsStateForName(state)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", state, err)
+		}
+	}
+
+	// get numbers
+	if data.year, err = self.getNbr(rec, "year"); err != nil {
+		return nil, err
+	}
+	if data.resident, err = self.getNbr(rec, "resident"); err != nil {
+		return nil, err
+	}
+	apportioned, err := utils.IsApportionedState(data.stateUsps)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", state, err)
+	}
+	if !apportioned || strings.ToLower(self.getVal(rec, "apportioned")) == "false" {
+		/* Not apportioned any seats */
+		return &data, nil
+	}
+	if len(self.getVal(rec, "apportionment")) > 0 {
+		n, err := self.getNbr(rec, "apportionment")
+		if err != nil {
+			return nil, err
+		}
+		data.apportionmentPop = &n
+		return &data, nil
+	}
+	if enslaved, err = self.getNbr(rec, "enslaved"); err != nil {
+		return nil, err
+	}
+	if indiansNotTaxed, err = self.getNbr(rec, "indians_not_taxed"); err != nil {
+		return nil, err
+	}
+	if overseas, err = self.getNbr(rec, "overseas"); err != nil {
+		return nil, err
+	}
+	n := computeApportionmentPop(data.year, data.resident, enslaved,
+		indiansNotTaxed, overseas)
+	data.apportionmentPop = &n
+	return &data, nil
+}
+
+func processDataFile(path string, sourceId int,
+	inserter *bulkInserter.Inserter) (int, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	reader := newCensusReader(f)
+	for {
+		rec, err := reader.read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, fmt.Errorf("%v: %v", path, err)
+		}
+
+		values := []interface{}{rec.year, rec.stateUsps, rec.resident,
+			rec.apportionmentPop, sourceId}
+		if err = inserter.Insert(values); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// updateCongressCensuses records which census governed the apportionment of
+// each congress.
+func updateCongressCensuses(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT nbr FROM congress")
+	if err != nil {
+		return err
+	}
+	var congresses []int
+	for rows.Next() {
+		var nbr int
+		if err = rows.Scan(&nbr); err != nil {
+			rows.Close()
+			return err
+		}
+		congresses = append(congresses, nbr)
+	}
+	rows.Close()
+
+	for _, nbr := range congresses {
+		var censusYear *int
+		if year, ok := apportionment.CensusForCongress(nbr); ok {
+			censusYear = &year
+		}
+		_, err = db.ExecContext(ctx,
+			"UPDATE congress SET census_year = $1 WHERE nbr = $2", censusYear, nbr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessCensusPops loads the census populations and maps each congress to
+// the census by which it was apportioned.
+func ProcessCensusPops(ctx context.Context, db *sql.DB, dataDirPath string) error {
+	paths, err := filepath.Glob(filepath.Join(dataDirPath, "census", "*.csv"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	// make source row
+	sourceText := "US Census Bureau, Decennial Census of Population, 1790-2020"
+	sourceId, err := utils.GetSource(ctx, db, sourceText)
+	if err != nil {
+		return err
+	}
+
+	// empty DB
+	_, err = db.ExecContext(ctx, "TRUNCATE census_state_pop")
+	if err != nil {
+		return err
+	}
+
+	// add entries to DB
+	cols := []string{"census_year", "state", "resident_pop",
+		"apportionment_pop", "source_id"}
+	inserter := bulkInserter.Make(ctx, db, "census_state_pop", cols)
+	n := 0
+	for _, path := range paths {
+		log.Printf("Processing %v", path)
+		nbrInFile, err := processDataFile(path, sourceId, &inserter)
+		if err != nil {
+			return err
+		}
+		n += nbrInFile
+	}
+	if err = inserter.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Inserted %v census records", n)
+
+	return updateCongressCensuses(ctx, db)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// BumpDataVersion increments the data version, which the API uses to tell
+// when its cached responses are stale.
+func BumpDataVersion(ctx context.Context, db *sql.DB) error {
+	sql := `INSERT INTO data_version(id, version, updated_at) VALUES (1, 1, now())
+	ON CONFLICT (id) DO UPDATE
+	SET version = data_version.version + 1, updated_at = now()
+	RETURNING version`
+	var version int64
+	if err := db.QueryRowContext(ctx, sql).Scan(&version); err != nil {
+		return err
+	}
+	log.Printf("Data version is now %v", version)
+	return nil
+}
@@ -2,4 +2,9 @@ module expandourhouse.com/loaddata
 
 go 1.13
 
-require github.com/lib/pq v1.2.0
+require (
+	expandourhouse.com/lib v0.0.0
+	github.com/lib/pq v1.2.0
+)
+
+replace expandourhouse.com/lib => ../../../lib
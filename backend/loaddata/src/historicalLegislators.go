@@ -13,6 +13,10 @@ import (
 	"expandourhouse.com/loaddata/utils"
 )
 
+// gHistoricalLegislatorsFile is the name of the legislators file in the data
+// dir.  It comes from https://github.com/unitedstates/congress-legislators.
+const gHistoricalLegislatorsFile = "legislators-historical.json"
+
 func parseDate(dateStr string) (time.Time, error) {
 	return time.Parse("2006-01-02", dateStr)
 }
@@ -27,11 +31,24 @@ func findCongress(ctx context.Context, db *sql.DB, date time.Time) (int, error)
 }
 
 func handleHistLegEntry(ctx context.Context, db *sql.DB,
-	entry map[string]interface{}, inserter *bulkInserter.Inserter) error {
+	entry map[string]interface{}, inserter *bulkInserter.Inserter,
+	legInserter *bulkInserter.Inserter) error {
 
 	id := entry["id"].(map[string]interface{})
 	bioguide := id["bioguide"].(string)
 
+	// add legislator
+	name := entry["name"].(map[string]interface{})
+	var middleName *string
+	if tmp, ok := name["middle"].(string); ok {
+		middleName = &tmp
+	}
+	legValues := []interface{}{bioguide, name["first"].(string), middleName,
+		name["last"].(string)}
+	if err := legInserter.Insert(legValues); err != nil {
+		return err
+	}
+
 	terms := entry["terms"].([]interface{})
 	for _, e := range terms {
 		term := e.(map[string]interface{})
@@ -58,8 +75,12 @@ func handleHistLegEntry(ctx context.Context, db *sql.DB,
 			return err
 		}
 
-		// get state
+		// get state and party
 		state := term["state"].(string)
+		var party *string
+		if tmp, ok := term["party"].(string); ok {
+			party = &tmp
+		}
 
 		// find district
 		districtNbr := int(term["district"].(float64))
@@ -74,7 +95,7 @@ func handleHistLegEntry(ctx context.Context, db *sql.DB,
 
 		// insert into DB
 		values := []interface{}{districtId, start, end, bioguide, state,
-			congressNbr}
+			congressNbr, party}
 		if err = inserter.Insert(values); err != nil {
 			return err
 		}
@@ -85,7 +106,7 @@ func handleHistLegEntry(ctx context.Context, db *sql.DB,
 
 func UpdateHistoricalLegislators(ctx context.Context, db *sql.DB, dataDirPath string) error {
 	// parse JSON
-	statesFilePath := filepath.Join(dataDirPath, "legislators-historical.json")
+	statesFilePath := filepath.Join(dataDirPath, gHistoricalLegislatorsFile)
 	f, err := os.Open(statesFilePath)
 	if err != nil {
 		return err
@@ -99,7 +120,7 @@ func UpdateHistoricalLegislators(ctx context.Context, db *sql.DB, dataDirPath st
 	}
 
 	// empty DB
-	_, err = db.ExecContext(ctx, "TRUNCATE representative_term")
+	_, err = db.ExecContext(ctx, "TRUNCATE representative_term, legislator")
 	if err != nil {
 		return err
 	}
@@ -107,10 +128,12 @@ func UpdateHistoricalLegislators(ctx context.Context, db *sql.DB, dataDirPath st
 	// add entries to DB
 	log.Print("Adding historial legislators")
 	cols := []string{"house_district_id", "start_date", "end_date",
-		"bioguide_id", "state", "congress_nbr"}
+		"bioguide_id", "state", "congress_nbr", "party"}
 	inserter := bulkInserter.Make(ctx, db, "representative_term", cols)
+	legCols := []string{"bioguide_id", "first_name", "middle_name", "last_name"}
+	legInserter := bulkInserter.Make(ctx, db, "legislator", legCols)
 	for _, entry := range data {
-		err = handleHistLegEntry(ctx, db, entry, &inserter)
+		err = handleHistLegEntry(ctx, db, entry, &inserter, &legInserter)
 		if err != nil {
 			return err
 		}
@@ -118,6 +141,9 @@ func UpdateHistoricalLegislators(ctx context.Context, db *sql.DB, dataDirPath st
 	if err = inserter.Flush(); err != nil {
 		return err
 	}
+	if err = legInserter.Flush(); err != nil {
+		return err
+	}
 
 	// refresh materialized view that finds the "irregular" states
 	_, err = db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW irregular_state")
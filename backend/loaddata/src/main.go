@@ -8,7 +8,11 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 
+	"expandourhouse.com/loaddata/censusPop"
+	"expandourhouse.com/loaddata/projections"
+	"expandourhouse.com/loaddata/shapes"
 	"expandourhouse.com/loaddata/tuftsTurnout"
 	"expandourhouse.com/loaddata/utils"
 	_ "github.com/lib/pq"
@@ -44,18 +48,25 @@ func run(dataDirPath string) error {
 	utils.LoadStateData(dataDirPath)
 
 	// load data
-	// log.Printf("Processing congress data")
-	// if err = UpdateCongresses(ctx, db, dataDirPath); err != nil {
-	// 	return err
-	// }
-	// log.Printf("Processing historical legislator data")
-	// if err = UpdateHistoricalLegislators(ctx, db, dataDirPath); err != nil {
-	// 	return err
-	// }
-	// _, err = db.ExecContext(ctx, "COMMIT")
-	// if err != nil {
-	// 	return err
-	// }
+	log.Printf("Processing congress data")
+	if err = UpdateCongresses(ctx, db, dataDirPath); err != nil {
+		return err
+	}
+	legislatorsPath := filepath.Join(dataDirPath, gHistoricalLegislatorsFile)
+	if _, err = os.Stat(legislatorsPath); err == nil {
+		log.Printf("Processing historical legislator data")
+		if err = UpdateHistoricalLegislators(ctx, db, dataDirPath); err != nil {
+			return err
+		}
+	} else if os.IsNotExist(err) {
+		log.Printf("Skipping historical legislator data: no %v", legislatorsPath)
+	} else {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "COMMIT")
+	if err != nil {
+		return err
+	}
 	// log.Printf("Processing CVAP data")
 	// if err = ProcessCvap(ctx, db, dataDirPath); err != nil {
 	// 	return err
@@ -76,6 +87,41 @@ func run(dataDirPath string) error {
 	if err != nil {
 		return err
 	}
+	log.Printf("Processing census population data")
+	if err = censusPop.ProcessCensusPops(ctx, db, dataDirPath); err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "COMMIT")
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Processing population projections")
+	if err = projections.ProcessProjections(ctx, db, dataDirPath); err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "COMMIT")
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Processing district and state shapes")
+	if err = shapes.ProcessShapes(ctx, db, dataDirPath); err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "COMMIT")
+	if err != nil {
+		return err
+	}
+
+	// tell the API that the data changed
+	if err = BumpDataVersion(ctx, db); err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "COMMIT")
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,158 @@
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/loaddata/bulkInserter"
+	"expandourhouse.com/loaddata/utils"
+)
+
+/*
+The Census Bureau publishes yearly estimates of the states' populations
+between censuses (e.g., NST-EST2024-ALLDATA.csv, which the Makefile
+downloads), but not projections.  From the files in the "estimates"
+subdirectory of the data directory, we make a middle projection for the next
+census by extending each state's trend over the estimates in a straight
+line.  The projections in the "projections" subdirectory take precedence.
+
+An estimates file has a NAME column, POPESTIMATEyyyy columns, and
+optionally a SUMLEV column, in which "040" marks a state; other rows (e.g.,
+regions) are skipped.
+*/
+
+var gEstimateColPattern = regexp.MustCompile(`^popestimate(\d{4})$`)
+
+const gStateSumLev = "040"
+
+// projectionKey identifies a projection; there may be only one for each.
+type projectionKey struct {
+	year      int
+	stateUsps string
+	scenario  string
+}
+
+// estimateCols finds the columns of the first and last estimates.
+func estimateCols(header []string) (firstYear, firstIdx, lastYear, lastIdx int,
+	err error) {
+
+	for idx, colName := range header {
+		m := gEstimateColPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(colName)))
+		if m == nil {
+			continue
+		}
+		year, _ := strconv.Atoi(m[1])
+		if firstYear == 0 || year < firstYear {
+			firstYear, firstIdx = year, idx
+		}
+		if year > lastYear {
+			lastYear, lastIdx = year, idx
+		}
+	}
+	if firstYear == lastYear {
+		err = fmt.Errorf("Need estimates for at least two years")
+	}
+	return
+}
+
+func processEstimatesFile(ctx context.Context, db *sql.DB, path string,
+	seen map[projectionKey]bool, sourceIds map[string]int,
+	inserter *bulkInserter.Inserter) (int, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// read header
+	reader := csv.NewReader(f)
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("%v: %v", path, err)
+	}
+	colNameToIdx := make(map[string]int)
+	for idx, colName := range header {
+		colNameToIdx[strings.ToLower(strings.TrimSpace(colName))] = idx
+	}
+	nameIdx, ok := colNameToIdx["name"]
+	if !ok {
+		return 0, fmt.Errorf("%v: Missing column: NAME", path)
+	}
+	sumLevIdx, hasSumLev := colNameToIdx["sumlev"]
+	firstYear, firstIdx, lastYear, lastIdx, err := estimateCols(header)
+	if err != nil {
+		return 0, fmt.Errorf("%v: %v", path, err)
+	}
+	censusYear := (lastYear/10 + 1) * 10
+
+	// get source
+	source := fmt.Sprintf("Extrapolated from US Census Bureau population estimates, %v-%v",
+		firstYear, lastYear)
+	sourceId, ok := sourceIds[source]
+	if !ok {
+		if sourceId, err = utils.GetSource(ctx, db, source); err != nil {
+			return 0, err
+		}
+		sourceIds[source] = sourceId
+	}
+
+	n := 0
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, fmt.Errorf("%v: %v", path, err)
+		}
+		if hasSumLev && strings.TrimSpace(rec[sumLevIdx]) != gStateSumLev {
+			continue
+		}
+
+		// get state
+		name := strings.TrimSpace(rec[nameIdx])
+		stateUsps, err := utils.GetUspsStateForName(name)
+		if err != nil {
+			return n, fmt.Errorf("%v: %v: %v", path, name, err)
+		}
+		apportioned, err := utils.IsApportionedState(stateUsps)
+		if err != nil {
+			return n, fmt.Errorf("%v: %v: %v", path, name, err)
+		}
+		key := projectionKey{censusYear, stateUsps, "middle"}
+		if !apportioned || seen[key] {
+			continue
+		}
+
+		// extrapolate
+		first, err := strconv.Atoi(strings.TrimSpace(rec[firstIdx]))
+		if err != nil {
+			return n, fmt.Errorf("%v: Bad estimate for %v: %v", path, name, err)
+		}
+		last, err := strconv.Atoi(strings.TrimSpace(rec[lastIdx]))
+		if err != nil {
+			return n, fmt.Errorf("%v: Bad estimate for %v: %v", path, name, err)
+		}
+		perYear := float64(last-first) / float64(lastYear-firstYear)
+		pop := last + int(perYear*float64(censusYear-lastYear))
+		if pop <= 0 {
+			return n, fmt.Errorf("%v: Extrapolated population of %v isn't positive", path,
+				name)
+		}
+
+		values := []interface{}{censusYear, stateUsps, "middle", pop, sourceId}
+		if err = inserter.Insert(values); err != nil {
+			return n, err
+		}
+		seen[key] = true
+		n++
+	}
+	return n, nil
+}
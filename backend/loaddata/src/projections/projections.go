@@ -0,0 +1,246 @@
+// Package projections loads projections of the states' populations at future
+// censuses, under low, middle, and high scenarios.
+//
+// The data is read from the CSV files in the "projections" subdirectory of
+// the data directory.  Each file begins with a header row; the columns may
+// be in any order:
+//
+//	year        Year of the projected census (required)
+//	state       USPS code or name of the state (required)
+//	scenario    "low", "middle" (or "mid" or "medium"), or "high" (required)
+//	population  Projected population (required)
+//	source      Who made the projection (e.g., "University of Virginia
+//	            Weldon Cooper Center, 2018"); defaults to the file's name
+//
+// Rows for DC and the territories (e.g., Puerto Rico) are skipped, since they
+// are not apportioned any seats.  Each year, state, and scenario may appear
+// only once across all the files.
+//
+// Middle projections for the next census are also made from the Census
+// Bureau's population estimates, if there are any (see estimates.go).
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/loaddata/bulkInserter"
+	"expandourhouse.com/loaddata/utils"
+)
+
+type projectionRec struct {
+	year      int
+	stateUsps string
+	scenario  string
+	pop       int
+	source    string
+}
+
+var gScenarioAliases = map[string]string{
+	"low":    "low",
+	"mid":    "middle",
+	"medium": "middle",
+	"middle": "middle",
+	"high":   "high",
+}
+
+type projectionReader struct {
+	csvReader     *csv.Reader
+	colNameToIdx  map[string]int
+	defaultSource string
+}
+
+func newProjectionReader(f *os.File, defaultSource string) *projectionReader {
+	r := &projectionReader{csv.NewReader(f), nil, defaultSource}
+	r.csvReader.ReuseRecord = true
+	return r
+}
+
+func (self *projectionReader) getVal(rec []string, col string) string {
+	idx, ok := self.colNameToIdx[col]
+	if !ok || idx >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[idx])
+}
+
+func (self *projectionReader) getNbr(rec []string, col string) (int, error) {
+	val := strings.Replace(self.getVal(rec, col), ",", "", -1)
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("Bad value for %v: %v", col, err)
+	}
+	return n, nil
+}
+
+func (self *projectionReader) read() (*projectionRec, error) {
+	var rec []string
+	var err error
+	var data projectionRec
+
+do:
+	rec, err = self.csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(rec) == 0 {
+		goto do
+	}
+
+	if self.colNameToIdx == nil {
+		// keep column names
+		self.colNameToIdx = make(map[string]int)
+		for idx, colName := range rec {
+			self.colNameToIdx[strings.ToLower(strings.TrimSpace(colName))] = idx
+		}
+		for _, col := range []string{"year", "state", "scenario", "population"} {
+			if _, ok := self.colNameToIdx[col]; !ok {
+				return nil, fmt.Errorf("Missing column: %v", col)
+			}
+		}
+		goto do
+	}
+
+	// get state
+	state := self.getVal(rec, "state")
+	if len(state) == 2 {
+		data.stateUsps = strings.ToUpper(state)
+	} else {
+		data.stateUsps, err = utils.GetUspsStateForName(state)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", state, err)
+		}
+	}
+	apportioned, err := utils.IsApportionedState(data.stateUsps)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", state, err)
+	}
+	if !apportioned {
+		/* DC and the territories are not apportioned any seats */
+		goto do
+	}
+
+	// get scenario
+	scenario := strings.ToLower(self.getVal(rec, "scenario"))
+	var ok bool
+	if data.scenario, ok = gScenarioAliases[scenario]; !ok {
+		return nil, fmt.Errorf("Unknown scenario: %q", scenario)
+	}
+
+	// get numbers
+	if data.year, err = self.getNbr(rec, "year"); err != nil {
+		return nil, err
+	}
+	if data.pop, err = self.getNbr(rec, "population"); err != nil {
+		return nil, err
+	}
+	if data.pop <= 0 {
+		return nil, fmt.Errorf("Population must be positive: %v", data.pop)
+	}
+
+	// get source
+	data.source = self.getVal(rec, "source")
+	if len(data.source) == 0 {
+		data.source = self.defaultSource
+	}
+	return &data, nil
+}
+
+func processDataFile(ctx context.Context, db *sql.DB, path string,
+	seen map[projectionKey]bool, sourceIds map[string]int,
+	inserter *bulkInserter.Inserter) (int, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	defaultSource := fmt.Sprintf("State population projections (%v)", filepath.Base(path))
+	reader := newProjectionReader(f, defaultSource)
+	for {
+		rec, err := reader.read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, fmt.Errorf("%v: %v", path, err)
+		}
+
+		// get source
+		sourceId, ok := sourceIds[rec.source]
+		if !ok {
+			if sourceId, err = utils.GetSource(ctx, db, rec.source); err != nil {
+				return n, err
+			}
+			sourceIds[rec.source] = sourceId
+		}
+
+		values := []interface{}{rec.year, rec.stateUsps, rec.scenario, rec.pop, sourceId}
+		if err = inserter.Insert(values); err != nil {
+			return n, err
+		}
+		seen[projectionKey{rec.year, rec.stateUsps, rec.scenario}] = true
+		n++
+	}
+	return n, nil
+}
+
+// ProcessProjections loads the population projections.
+func ProcessProjections(ctx context.Context, db *sql.DB, dataDirPath string) error {
+	paths, err := filepath.Glob(filepath.Join(dataDirPath, "projections", "*.csv"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	// empty DB
+	_, err = db.ExecContext(ctx, "TRUNCATE state_pop_projection")
+	if err != nil {
+		return err
+	}
+
+	// add entries to DB
+	cols := []string{"year", "state", "scenario", "pop", "source_id"}
+	inserter := bulkInserter.Make(ctx, db, "state_pop_projection", cols)
+	sourceIds := make(map[string]int)
+	seen := make(map[projectionKey]bool)
+	n := 0
+	for _, path := range paths {
+		log.Printf("Processing %v", path)
+		nbrInFile, err := processDataFile(ctx, db, path, seen, sourceIds, &inserter)
+		if err != nil {
+			return err
+		}
+		n += nbrInFile
+	}
+
+	// extrapolate estimates
+	paths, err = filepath.Glob(filepath.Join(dataDirPath, "estimates", "*.csv"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		log.Printf("Processing %v", path)
+		nbrInFile, err := processEstimatesFile(ctx, db, path, seen, sourceIds, &inserter)
+		if err != nil {
+			return err
+		}
+		n += nbrInFile
+	}
+	if err = inserter.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Inserted %v projection records", n)
+	return nil
+}
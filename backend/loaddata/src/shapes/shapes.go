@@ -0,0 +1,262 @@
+// Package shapes loads the shapes of the districts and states of each
+// congress.
+//
+// The shapes are read from the "shapes" subdirectory of the data directory,
+// which should contain the GeoJSON files that map-data makes for the
+// tilesets: NNN-proc-districts.geojson and NNN-proc-states.geojson, where NNN
+// is the number of the congress.  The files may hold a FeatureCollection or
+// one feature per line (as process-districts writes them).
+//
+// The districts must already be in the DB (e.g., from the legislators); the
+// shapes of other districts are skipped.
+package shapes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/lib/districtid"
+	"expandourhouse.com/loaddata/bulkInserter"
+	"expandourhouse.com/loaddata/utils"
+)
+
+const gDistrictsSuffix = "-proc-districts.geojson"
+const gStatesSuffix = "-proc-states.geojson"
+
+/*
+We don't need to look inside the geometries, so we keep them as raw JSON and
+store them as they are.
+*/
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   json.RawMessage        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	Features   []*feature             `json:"features"` /* if this is a FeatureCollection */
+}
+
+// isBoundary returns whether the given feature is a boundary of the given
+// type (as opposed to, e.g., a label).
+func (self *feature) isBoundary(boundaryType string) bool {
+	return self.Properties["group"] == "boundary" && self.Properties["type"] == boundaryType
+}
+
+// forEachFeature calls f on each feature in the given GeoJSON file.
+func forEachFeature(path string, f func(*feature) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	for {
+		var feat feature
+		err = decoder.Decode(&feat)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+
+		if feat.Type == "FeatureCollection" {
+			for _, member := range feat.Features {
+				if err = f(member); err != nil {
+					return err
+				}
+			}
+		} else if err = f(&feat); err != nil {
+			return err
+		}
+	}
+}
+
+// congressForPath returns the congress whose shapes are in the file at the
+// given path.
+func congressForPath(path, suffix string) (int, error) {
+	name := strings.TrimSuffix(filepath.Base(path), suffix)
+	congress, err := strconv.Atoi(name)
+	if err != nil {
+		return 0, fmt.Errorf("Bad shapes file name: %v", path)
+	}
+	return congress, nil
+}
+
+type districtKey struct {
+	state    string
+	district int
+}
+
+// getDistrictIds returns the IDs of the table rows of the districts in the
+// given congress.
+func getDistrictIds(ctx context.Context, db *sql.DB,
+	congress int) (map[districtKey]int, error) {
+
+	rows, err := db.QueryContext(ctx, `SELECT id, state, district FROM house_district
+		WHERE congress_nbr = $1`, congress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[districtKey]int)
+	for rows.Next() {
+		var rowId int
+		var key districtKey
+		if err = rows.Scan(&rowId, &key.state, &key.district); err != nil {
+			return nil, err
+		}
+		result[key] = rowId
+	}
+	return result, rows.Err()
+}
+
+func processDistrictsFile(ctx context.Context, db *sql.DB, path string,
+	inserter *bulkInserter.Inserter) (int, error) {
+
+	congress, err := congressForPath(path, gDistrictsSuffix)
+	if err != nil {
+		return 0, err
+	}
+	districtIds, err := getDistrictIds(ctx, db, congress)
+	if err != nil {
+		return 0, err
+	}
+
+	n, nbrSkipped := 0, 0
+	err = forEachFeature(path, func(feat *feature) error {
+		if !feat.isBoundary("district") {
+			return nil
+		}
+
+		/* Parse the ID like process-districts does, so IDs match the tilesets */
+		shapeId, ok := feat.Properties["id"].(string)
+		if !ok {
+			return fmt.Errorf("%v: Feature doesn't have ID", path)
+		}
+		id, err := districtid.Parse(shapeId)
+		if err != nil {
+			return fmt.Errorf("%v: %v", path, err)
+		}
+		if id.District < 0 {
+			// not a real district (e.g., Indian lands)
+			return nil
+		}
+
+		state, err := utils.GetUspsStateForFips(id.StateFips)
+		if err != nil {
+			return fmt.Errorf("%v: %v: %v", path, shapeId, err)
+		}
+
+		/*
+			Shapes only go with districts that we have data for; adding
+			districts for the others would make them count as seats.
+		*/
+		districtId, ok := districtIds[districtKey{state, id.District}]
+		if !ok {
+			nbrSkipped++
+			return nil
+		}
+
+		// add to DB
+		values := []interface{}{districtId, shapeId, string(feat.Geometry)}
+		if err = inserter.Insert(values); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if nbrSkipped > 0 {
+		log.Printf("%v: skipped %v shapes of unknown districts", path, nbrSkipped)
+	}
+	return n, err
+}
+
+func processStatesFile(ctx context.Context, path string,
+	inserter *bulkInserter.Inserter) (int, error) {
+
+	congress, err := congressForPath(path, gStatesSuffix)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	err = forEachFeature(path, func(feat *feature) error {
+		if !feat.isBoundary("state") {
+			return nil
+		}
+		state, ok := feat.Properties["state"].(string)
+		if !ok {
+			return fmt.Errorf("%v: Feature doesn't have state", path)
+		}
+		values := []interface{}{state, congress, string(feat.Geometry)}
+		if err := inserter.Insert(values); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// ProcessShapes replaces the district and state shapes in the DB with the
+// ones in the data directory.
+func ProcessShapes(ctx context.Context, db *sql.DB, dataDirPath string) error {
+	// empty DB
+	_, err := db.ExecContext(ctx, "TRUNCATE house_district_shape, state_shape")
+	if err != nil {
+		return err
+	}
+
+	// add district shapes
+	paths, err := filepath.Glob(filepath.Join(dataDirPath, "shapes", "*"+gDistrictsSuffix))
+	if err != nil {
+		return err
+	}
+	cols := []string{"house_district_id", "shape_id", "geometry"}
+	inserter := bulkInserter.Make(ctx, db, "house_district_shape", cols)
+	n := 0
+	for _, path := range paths {
+		log.Printf("Processing %v", path)
+		nbrInFile, err := processDistrictsFile(ctx, db, path, &inserter)
+		if err != nil {
+			return err
+		}
+		n += nbrInFile
+	}
+	if err = inserter.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Inserted %v district shapes", n)
+
+	// add state shapes
+	paths, err = filepath.Glob(filepath.Join(dataDirPath, "shapes", "*"+gStatesSuffix))
+	if err != nil {
+		return err
+	}
+	cols = []string{"state", "congress_nbr", "geometry"}
+	inserter = bulkInserter.Make(ctx, db, "state_shape", cols)
+	n = 0
+	for _, path := range paths {
+		log.Printf("Processing %v", path)
+		nbrInFile, err := processStatesFile(ctx, path, &inserter)
+		if err != nil {
+			return err
+		}
+		n += nbrInFile
+	}
+	if err = inserter.Flush(); err != nil {
+		return err
+	}
+	log.Printf("Inserted %v state shapes", n)
+
+	return nil
+}
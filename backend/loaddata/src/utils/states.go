@@ -17,6 +17,10 @@ type stateDataEntry struct {
 var gEntries []*stateDataEntry
 var gFipsToIdx map[int]int
 var gNameToIdx map[string]int
+var gUspsToIdx map[string]int
+
+/* States with higher FIPS codes are territories */
+const gMaxStateFips = 56
 
 func LoadStateData(dataDirPath string) error {
 	if gEntries != nil {
@@ -44,9 +48,11 @@ func LoadStateData(dataDirPath string) error {
 	// process entries
 	gFipsToIdx = make(map[int]int)
 	gNameToIdx = make(map[string]int)
+	gUspsToIdx = make(map[string]int)
 	for idx, entry := range gEntries {
 		gFipsToIdx[entry.FIPS] = idx
 		gNameToIdx[strings.ToLower(entry.Name)] = idx
+		gUspsToIdx[entry.USPS] = idx
 	}
 
 	return nil
@@ -75,3 +81,18 @@ func GetUspsStateForName(name string) (string, error) {
 	}
 	return gEntries[idx].USPS, nil
 }
+
+// IsApportionedState returns whether the state with the given USPS code is
+// apportioned seats in the House (i.e., it is not DC or a territory).
+func IsApportionedState(usps string) (bool, error) {
+	if gEntries == nil {
+		return false, errors.New("State data not loaded")
+	}
+
+	idx, ok := gUspsToIdx[usps]
+	if !ok {
+		return false, errors.New("No such state")
+	}
+	entry := gEntries[idx]
+	return entry.USPS != "DC" && entry.FIPS <= gMaxStateFips, nil
+}
@@ -16,13 +16,20 @@ func GetSource(ctx context.Context, db *sql.DB, text string) (int, error) {
 	}
 	if rows.Next() {
 		err = rows.Scan(&id)
+		if err != nil {
+			goto done
+		}
+
+		// note that we're loading from it again
+		rows.Close()
+		_, err = db.ExecContext(ctx, "UPDATE source SET loaded_at = now() WHERE id = $1", id)
 		goto done
 	}
 
 	// make source row
 	rows.Close()
-	rows, err = db.QueryContext(ctx, "INSERT INTO source(name) VALUES ($1) RETURNING id",
-		text)
+	rows, err = db.QueryContext(ctx,
+		"INSERT INTO source(name, loaded_at) VALUES ($1, now()) RETURNING id", text)
 	if err != nil {
 		goto done
 	}
@@ -1,5 +1,7 @@
 ROOT = ..
 
+DOCKER_CONTEXT ?= .
+
 PROXY_DIR = ${ROOT}/.done-proxies
 DOCKER_COMPOSE = cd "${ROOT}/local-dev" && docker-compose
 DB_VOLUME = local-dev_db-data
@@ -17,7 +19,7 @@ help:
 build: ${PROXY_DIR}/image
 
 ${PROXY_DIR}/image: ${SOURCE}
-	docker build -t "${DOCKER_IMAGE}" .
+	docker build -t "${DOCKER_IMAGE}" -f Dockerfile "${DOCKER_CONTEXT}"
 	mkdir -p "${PROXY_DIR}" && touch "$@"
 
 .PHONY: run
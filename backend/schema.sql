@@ -1,13 +1,26 @@
+/*
+The statements are idempotent, so that running this file against an existing
+DB (e.g., psql -f schema.sql) brings it up to date.
+*/
+
 CREATE TABLE IF NOT EXISTS source(
     id SERIAL NOT NULL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL
+    name VARCHAR(255) NOT NULL,
+    loaded_at TIMESTAMP WITH TIME ZONE /* When loaddata last loaded data from this source */
 );
 
+/* For DBs made before loaded_at was added */
+ALTER TABLE source ADD COLUMN IF NOT EXISTS loaded_at TIMESTAMP WITH TIME ZONE;
+
 CREATE TABLE IF NOT EXISTS congress(
     nbr INTEGER NOT NULL PRIMARY KEY, /* E.g., 115 for 115th */
-    start_year INTEGER NOT NULL
+    start_year INTEGER NOT NULL,
+    census_year INTEGER /* Census by which the House was apportioned; NULL for the 1st and 2nd */
 );
 
+/* For DBs made before census_year was added */
+ALTER TABLE congress ADD COLUMN IF NOT EXISTS census_year INTEGER;
+
 CREATE TABLE IF NOT EXISTS house_district(
     id SERIAL NOT NULL PRIMARY KEY,
     state VARCHAR(2) NOT NULL, /* Two-digit state FIPS code */
@@ -33,6 +46,53 @@ CREATE TABLE IF NOT EXISTS house_district_turnout(
     source_id INTEGER NOT NULL REFERENCES source(id) ON DELETE RESTRICT
 );
 
+/* Shapes are GeoJSON geometries (in WGS 84) made by map-data */
+CREATE TABLE IF NOT EXISTS house_district_shape(
+    house_district_id INTEGER NOT NULL REFERENCES house_district(id) ON DELETE CASCADE,
+    shape_id VARCHAR(32) NOT NULL, /* ID of the district in the tilesets */
+    geometry TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS house_district_shape_district
+ON house_district_shape(house_district_id);
+
+CREATE TABLE IF NOT EXISTS state_shape(
+    state VARCHAR(16) NOT NULL, /* Abbreviation (normally the USPS code) */
+    congress_nbr INTEGER NOT NULL REFERENCES congress(nbr) ON DELETE RESTRICT,
+    geometry TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS state_shape_congress ON state_shape(congress_nbr);
+
+CREATE TABLE IF NOT EXISTS census_state_pop(
+    census_year INTEGER NOT NULL,
+    state VARCHAR(2) NOT NULL, /* USPS code */
+    resident_pop INTEGER NOT NULL,
+    apportionment_pop INTEGER, /* NULL if not apportioned any seats (e.g., territories) */
+    source_id INTEGER NOT NULL REFERENCES source(id) ON DELETE RESTRICT,
+
+    CONSTRAINT census_state_pop_unique UNIQUE (census_year, state)
+);
+
+CREATE TABLE IF NOT EXISTS state_pop_projection(
+    year INTEGER NOT NULL, /* Year of the (future) census */
+    state VARCHAR(2) NOT NULL, /* USPS code */
+    scenario VARCHAR(8) NOT NULL, /* "low", "middle", or "high" */
+    pop INTEGER NOT NULL,
+    source_id INTEGER NOT NULL REFERENCES source(id) ON DELETE RESTRICT,
+
+    CONSTRAINT state_pop_projection_unique UNIQUE (year, state, scenario),
+    CONSTRAINT state_pop_projection_scenario CHECK (scenario IN ('low', 'middle', 'high')),
+    CONSTRAINT state_pop_projection_pop_is_pos CHECK (pop > 0)
+);
+
+CREATE TABLE IF NOT EXISTS legislator(
+    bioguide_id VARCHAR(16) NOT NULL PRIMARY KEY,
+    first_name VARCHAR(255) NOT NULL,
+    middle_name VARCHAR(255),
+    last_name VARCHAR(255) NOT NULL
+);
+
 CREATE TABLE IF NOT EXISTS representative_term(
     id SERIAL NOT NULL PRIMARY KEY,
     house_district_id INTEGER REFERENCES house_district(id) ON DELETE CASCADE,
@@ -41,11 +101,26 @@ CREATE TABLE IF NOT EXISTS representative_term(
     bioguide_id VARCHAR(16) NOT NULL,
     state VARCHAR(2) NOT NULL, /* Two-digit state FIPS code */
     congress_nbr INTEGER NOT NULL REFERENCES congress(nbr) ON DELETE RESTRICT,
+    party VARCHAR(64), /* NULL == unknown */
 
     CONSTRAINT rep_term_dates CHECK (start_date <= end_date)
 );
 
-CREATE VIEW state_with_overlapping_terms AS
+/* For DBs made before party was added */
+ALTER TABLE representative_term ADD COLUMN IF NOT EXISTS party VARCHAR(64);
+
+/*
+Bumped by loaddata after every successful load; the API uses it to cache
+responses.  Until it exists in an older DB, the API treats the data as
+unversioned.
+*/
+CREATE TABLE IF NOT EXISTS data_version(
+    id INTEGER NOT NULL PRIMARY KEY CHECK (id = 1), /* There is only one row */
+    version INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE OR REPLACE VIEW state_with_overlapping_terms AS
 SELECT DISTINCT t1.state, t1.congress_nbr
 /* Collect all pairs of rep terms for the same state, district, and congress */
 FROM representative_term t1 JOIN representative_term t2
@@ -54,29 +129,29 @@ FROM representative_term t1 JOIN representative_term t2
 /* Find ones that overlap in time */
 WHERE t1.start_date <= t2.start_date AND t1.end_date > t2.start_date;
 
-CREATE VIEW state_with_unknown_district AS
+CREATE OR REPLACE VIEW state_with_unknown_district AS
 SELECT DISTINCT state, congress_nbr
 FROM representative_term
 WHERE house_district_id IS NULL;
 
-CREATE VIEW state_with_atlarge_district AS
+CREATE OR REPLACE VIEW state_with_atlarge_district AS
 SELECT DISTINCT term.state, term.congress_nbr
 FROM representative_term term JOIN house_district dist
     ON (term.house_district_id = dist.id)
 WHERE dist.district = 0; /* 0 = at-large */
 
-CREATE VIEW state_with_nonatlarge_district AS
+CREATE OR REPLACE VIEW state_with_nonatlarge_district AS
 SELECT DISTINCT term.state, term.congress_nbr
 FROM representative_term term JOIN house_district dist
     ON (term.house_district_id = dist.id)
 WHERE dist.district > 0;
 
-CREATE VIEW state_with_atlarge_and_nonatlarge_districts AS
+CREATE OR REPLACE VIEW state_with_atlarge_and_nonatlarge_districts AS
 (SELECT state, congress_nbr FROM state_with_atlarge_district)
 INTERSECT
 (SELECT state, congress_nbr FROM state_with_nonatlarge_district);
 
-CREATE MATERIALIZED VIEW irregular_state AS
+CREATE MATERIALIZED VIEW IF NOT EXISTS irregular_state AS
 (SELECT state, congress_nbr FROM state_with_atlarge_and_nonatlarge_districts)
 UNION
 (SELECT state, congress_nbr FROM state_with_unknown_district)
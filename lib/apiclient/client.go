@@ -0,0 +1,195 @@
+// Package apiclient is a client for the expandourhouse API (backend/api).
+//
+// All methods take a context and retry GET requests that fail because of
+// network errors or because the server is temporarily unavailable.
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const gDefaultMaxRetries = 3
+const gDefaultRetryDelay = 500 * time.Millisecond
+
+type Client struct {
+	// BaseURL is the URL of the server, without the "/api" (e.g.,
+	// "http://localhost:8081").
+	BaseURL string
+
+	HTTPClient *http.Client
+
+	// MaxRetries is the number of times to retry a failed request.
+	MaxRetries int
+
+	// RetryDelay is the time to wait before the first retry.  It doubles
+	// with each retry.
+	RetryDelay time.Duration
+
+	UserAgent string
+}
+
+// New returns a client for the server at the given URL.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		MaxRetries: gDefaultMaxRetries,
+		RetryDelay: gDefaultRetryDelay,
+		UserAgent:  "expandourhouse-apiclient",
+	}
+}
+
+// ParamError says what is wrong with a param of a request.
+type ParamError struct {
+	Param   string `json:"param"`
+	Problem string `json:"problem"`
+}
+
+// Error is an error response from the API.
+type Error struct {
+	StatusCode int
+	Code       string       `json:"code"`
+	Message    string       `json:"message"`
+	RequestID  string       `json:"requestId"`
+	Details    []ParamError `json:"details"`
+}
+
+func (self *Error) Error() string {
+	msg := fmt.Sprintf("%v (%v)", self.Message, self.StatusCode)
+	for _, d := range self.Details {
+		msg += fmt.Sprintf("; %v: %v", d.Param, d.Problem)
+	}
+	return msg
+}
+
+// IsNotFound returns whether err is an API error saying that the requested
+// thing doesn't exist.
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+func parseError(resp *http.Response) error {
+	apiErr := Error{StatusCode: resp.StatusCode}
+	var body struct {
+		Error *Error `json:"error"`
+	}
+	body.Error = &apiErr
+	data, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(data, &body); err != nil || len(apiErr.Code) == 0 {
+		/* Not from the API (e.g., from a proxy) */
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	apiErr.StatusCode = resp.StatusCode
+	return &apiErr
+}
+
+func retryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// get sends a GET request for the given path (which starts with "/api") and
+// query, retrying if necessary.  If the response is successful, the caller
+// must close its body.
+func (self *Client) get(ctx context.Context, path string, query url.Values,
+	accept string) (*http.Response, error) {
+
+	u := self.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	delay := self.RetryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Accept", accept)
+		if len(self.UserAgent) > 0 {
+			req.Header.Set("User-Agent", self.UserAgent)
+		}
+
+		resp, err := self.HTTPClient.Do(req)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		if err == nil {
+			err = parseError(resp)
+			resp.Body.Close()
+			if !retryable(resp.StatusCode) {
+				return nil, err
+			}
+		}
+		if attempt >= self.MaxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		// wait before retrying
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// getJSON sends a GET request and decodes the JSON response into result.
+func (self *Client) getJSON(ctx context.Context, path string, query url.Values,
+	result interface{}) error {
+
+	resp, err := self.get(ctx, path, query, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// Format is a format in which districts can be exported.
+type Format string
+
+const (
+	CSV     Format = "csv"
+	GeoJSON Format = "geojson"
+)
+
+// export sends a GET request for the given path in the given format.  The
+// caller must close the result.
+func (self *Client) export(ctx context.Context, path string,
+	format Format) (io.ReadCloser, error) {
+
+	resp, err := self.get(ctx, path+"."+string(format), nil, "*/*")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func congressPath(congress int) string {
+	return "/api/congresses/" + strconv.Itoa(congress)
+}
+
+func statePath(congress int, state string) string {
+	return congressPath(congress) + "/states/" + url.PathEscape(state)
+}
+
+func districtPath(congress int, state string, district int) string {
+	return statePath(congress, state) + "/districts/" + strconv.Itoa(district)
+}
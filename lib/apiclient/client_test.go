@@ -0,0 +1,251 @@
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a server that calls handler with the number of the
+// attempt (starting at 1), and a client for it that doesn't wait long
+// before retrying.
+func newTestServer(handler func(w http.ResponseWriter, r *http.Request, attempt int)) (
+	*httptest.Server, *Client, *int32) {
+
+	var nbrAttempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, int(atomic.AddInt32(&nbrAttempts, 1)))
+	}))
+	client := New(server.URL)
+	client.RetryDelay = time.Millisecond
+	return server, client, &nbrAttempts
+}
+
+func writeBody(w http.ResponseWriter, statusCode int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(body))
+}
+
+func TestDistrictFacts(t *testing.T) {
+	server, client, _ := newTestServer(func(w http.ResponseWriter, r *http.Request, attempt int) {
+		if r.URL.Path != "/api/congresses/115/states/CA/districts/2" {
+			writeBody(w, http.StatusNotFound, `{"error":{"code":"not_found","message":"Not found"}}`)
+			return
+		}
+		writeBody(w, http.StatusOK, `{
+			"all": {"value": 700000, "source": "Census"},
+			"cvap": {"value": 450000, "source": "ACS", "marginOfError": 5000},
+			"turnout": {"value": 300000, "source": "MIT", "disagreement": true,
+				"sources": [{"value": 300000, "source": "MIT"},
+					{"value": 250000, "source": "Lampi"}]}
+		}`)
+	})
+	defer server.Close()
+
+	facts, err := client.District(context.Background(), 115, "CA", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moe := 5000
+	want := DistrictFacts{
+		"all":  {Value: 700000, Source: "Census"},
+		"cvap": {Value: 450000, Source: "ACS", MarginOfError: &moe},
+		"turnout": {Value: 300000, Source: "MIT", Disagreement: true, Sources: []*Fact{
+			{Value: 300000, Source: "MIT"},
+			{Value: 250000, Source: "Lampi"},
+		}},
+	}
+	if !reflect.DeepEqual(facts, want) {
+		t.Errorf("Got %+v; want %+v", facts, want)
+	}
+	if facts["all"].MarginOfError != nil {
+		t.Error("A count has a margin of error")
+	}
+	if facts.Turnout() != facts["turnout"] {
+		t.Error("Turnout() doesn't return the turnout")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	cases := []struct {
+		name         string
+		handler      func(w http.ResponseWriter, r *http.Request, attempt int)
+		wantErr      bool
+		wantAttempts int32
+	}{
+		{
+			name: "503 then success",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				if attempt < 3 {
+					writeBody(w, http.StatusServiceUnavailable, "")
+					return
+				}
+				writeBody(w, http.StatusOK, `{"115": {"startYear": 2017}}`)
+			},
+			wantAttempts: 3,
+		},
+		{
+			name: "connection error then success",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				if attempt == 1 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err == nil {
+						conn.Close()
+					}
+					return
+				}
+				writeBody(w, http.StatusOK, `{"115": {"startYear": 2017}}`)
+			},
+			wantAttempts: 2,
+		},
+		{
+			name: "always 503",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				writeBody(w, http.StatusServiceUnavailable, "")
+			},
+			wantErr:      true,
+			wantAttempts: gDefaultMaxRetries + 1,
+		},
+		{
+			name: "404",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				writeBody(w, http.StatusNotFound, `{"error":{"code":"not_found","message":"Not found"}}`)
+			},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+		{
+			name: "400",
+			handler: func(w http.ResponseWriter, r *http.Request, attempt int) {
+				writeBody(w, http.StatusBadRequest, `{"error":{"code":"bad_request","message":"Bad"}}`)
+			},
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+	}
+	for _, c := range cases {
+		server, client, nbrAttempts := newTestServer(c.handler)
+		cons, err := client.Congresses(context.Background())
+		server.Close()
+		if c.wantErr && err == nil {
+			t.Errorf("%v: got %v; want an error", c.name, cons)
+		} else if !c.wantErr && err != nil {
+			t.Errorf("%v: %v", c.name, err)
+		} else if !c.wantErr && !reflect.DeepEqual(cons, []Congress{{115, 2017}}) {
+			t.Errorf("%v: got %v", c.name, cons)
+		}
+		if n := atomic.LoadInt32(nbrAttempts); n != c.wantAttempts {
+			t.Errorf("%v: got %v attempts; want %v", c.name, n, c.wantAttempts)
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	server, client, _ := newTestServer(func(w http.ResponseWriter, r *http.Request, attempt int) {
+		switch r.URL.Path {
+		case "/api/congresses/115/states/CA/districts/99":
+			writeBody(w, http.StatusNotFound, `{"error":{"code":"not_found",
+				"message":"No such district","requestId":"abc123"}}`)
+		case "/api/congresses/115/apportionment":
+			writeBody(w, http.StatusBadRequest, `{"error":{"code":"invalid_parameters",
+				"message":"Invalid parameters","requestId":"def456",
+				"details":[{"param":"method","problem":"Unknown method"}]}}`)
+		default:
+			/* e.g., a proxy's page */
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<html>Forbidden</html>"))
+		}
+	})
+	defer server.Close()
+	ctx := context.Background()
+
+	_, err := client.District(ctx, 115, "CA", 99)
+	apiErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Got %T; want *Error", err)
+	}
+	want := Error{StatusCode: 404, Code: "not_found", Message: "No such district",
+		RequestID: "abc123"}
+	if !reflect.DeepEqual(*apiErr, want) {
+		t.Errorf("Got %+v; want %+v", *apiErr, want)
+	}
+	if !IsNotFound(err) {
+		t.Error("IsNotFound is false for a 404")
+	}
+
+	_, err = client.Apportionment(ctx, 115, ApportionmentOptions{Method: "xyz"})
+	apiErr, ok = err.(*Error)
+	if !ok {
+		t.Fatalf("Got %T; want *Error", err)
+	}
+	want = Error{StatusCode: 400, Code: "invalid_parameters", Message: "Invalid parameters",
+		RequestID: "def456",
+		Details:   []ParamError{{Param: "method", Problem: "Unknown method"}}}
+	if !reflect.DeepEqual(*apiErr, want) {
+		t.Errorf("Got %+v; want %+v", *apiErr, want)
+	}
+	if IsNotFound(err) {
+		t.Error("IsNotFound is true for a 400")
+	}
+
+	_, err = client.Congresses(ctx)
+	apiErr, ok = err.(*Error)
+	if !ok {
+		t.Fatalf("Got %T; want *Error", err)
+	}
+	if apiErr.StatusCode != 403 || apiErr.Message != "Forbidden" || len(apiErr.Code) > 0 {
+		t.Errorf("Got %+v for a non-API error", *apiErr)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	// while waiting to retry
+	server, client, nbrAttempts := newTestServer(func(w http.ResponseWriter, r *http.Request, attempt int) {
+		writeBody(w, http.StatusServiceUnavailable, "")
+	})
+	client.RetryDelay = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	start := time.Now()
+	_, err := client.Congresses(ctx)
+	cancel()
+	server.Close()
+	if err != context.DeadlineExceeded {
+		t.Errorf("Got %v; want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Took %v to give up", elapsed)
+	}
+	if n := atomic.LoadInt32(nbrAttempts); n != 1 {
+		t.Errorf("Got %v attempts; want 1", n)
+	}
+
+	// while waiting for the response
+	unblock := make(chan struct{})
+	server, client, nbrAttempts = newTestServer(func(w http.ResponseWriter, r *http.Request, attempt int) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	})
+	defer server.Close()
+	defer close(unblock)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start = time.Now()
+	_, err = client.Congresses(ctx)
+	if err == nil {
+		t.Error("Got no error after cancellation")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Took %v to give up", elapsed)
+	}
+	if n := atomic.LoadInt32(nbrAttempts); n != 1 {
+		t.Errorf("Got %v attempts; want 1", n)
+	}
+}
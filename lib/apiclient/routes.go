@@ -0,0 +1,264 @@
+package apiclient
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/lib/proposals"
+)
+
+// Congresses returns the congresses, in order.
+func (self *Client) Congresses(ctx context.Context) ([]Congress, error) {
+	var infos map[int]struct {
+		StartYear int `json:"startYear"`
+	}
+	if err := self.getJSON(ctx, "/api/congresses", nil, &infos); err != nil {
+		return nil, err
+	}
+
+	result := make([]Congress, 0, len(infos))
+	for nbr, info := range infos {
+		result = append(result, Congress{Nbr: nbr, StartYear: info.StartYear})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Nbr < result[j].Nbr })
+	return result, nil
+}
+
+// ExportCongresses returns the congresses as CSV.  The caller must close the
+// result.
+func (self *Client) ExportCongresses(ctx context.Context) (io.ReadCloser, error) {
+	return self.export(ctx, "/api/congresses", CSV)
+}
+
+// States returns the states in the given congress, with their districts'
+// facts.
+func (self *Client) States(ctx context.Context, congress int) (map[string]*State, error) {
+	var result map[string]*State
+	err := self.getJSON(ctx, congressPath(congress)+"/states", nil, &result)
+	return result, err
+}
+
+// ExportStates returns the districts of the given congress, with their
+// facts, in the given format.  The caller must close the result.
+func (self *Client) ExportStates(ctx context.Context, congress int,
+	format Format) (io.ReadCloser, error) {
+
+	return self.export(ctx, congressPath(congress)+"/states", format)
+}
+
+// State returns the given state, with its districts' facts.
+func (self *Client) State(ctx context.Context, congress int, state string) (*State, error) {
+	var result State
+	if err := self.getJSON(ctx, statePath(congress, state), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportState returns the districts of the given state, with their facts, in
+// the given format.  The caller must close the result.
+func (self *Client) ExportState(ctx context.Context, congress int, state string,
+	format Format) (io.ReadCloser, error) {
+
+	return self.export(ctx, statePath(congress, state), format)
+}
+
+// District returns the facts about the given district.
+func (self *Client) District(ctx context.Context, congress int, state string,
+	district int) (DistrictFacts, error) {
+
+	var result DistrictFacts
+	err := self.getJSON(ctx, districtPath(congress, state, district), nil, &result)
+	return result, err
+}
+
+// ExportDistrict returns the given district, with its facts, in the given
+// format.  The caller must close the result.
+func (self *Client) ExportDistrict(ctx context.Context, congress int, state string,
+	district int, format Format) (io.ReadCloser, error) {
+
+	return self.export(ctx, districtPath(congress, state, district), format)
+}
+
+// StateIrregularities explains why the given state is irregular (if it is).
+func (self *Client) StateIrregularities(ctx context.Context, congress int,
+	state string) (*StateIrregularities, error) {
+
+	var result StateIrregularities
+	path := statePath(congress, state) + "/irregularities"
+	if err := self.getJSON(ctx, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StateRepresentatives returns the terms of the given state's
+// representatives.
+func (self *Client) StateRepresentatives(ctx context.Context, congress int,
+	state string) ([]*Representative, error) {
+
+	var result []*Representative
+	err := self.getJSON(ctx, statePath(congress, state)+"/representatives", nil, &result)
+	return result, err
+}
+
+// DistrictRepresentatives returns the terms of the given district's
+// representatives.
+func (self *Client) DistrictRepresentatives(ctx context.Context, congress int,
+	state string, district int) ([]*Representative, error) {
+
+	var result []*Representative
+	err := self.getJSON(ctx, districtPath(congress, state, district)+"/representatives",
+		nil, &result)
+	return result, err
+}
+
+// Apportionment reapportions the given congress's House.
+func (self *Client) Apportionment(ctx context.Context, congress int,
+	opts ApportionmentOptions) (*Apportionment, error) {
+
+	query := make(url.Values)
+	if opts.Size > 0 {
+		query.Set("size", strconv.Itoa(opts.Size))
+	}
+	if len(opts.Method) > 0 {
+		query.Set("method", string(opts.Method))
+	}
+	var result Apportionment
+	path := congressPath(congress) + "/apportionment"
+	if err := self.getJSON(ctx, path, query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PriorityList returns the order in which the seats would be assigned
+// according to the given census.
+func (self *Client) PriorityList(ctx context.Context, censusYear int,
+	opts PriorityListOptions) (*PriorityList, error) {
+
+	query := make(url.Values)
+	if opts.Through > 0 {
+		query.Set("through", strconv.Itoa(opts.Through))
+	}
+	if len(opts.Method) > 0 {
+		query.Set("method", string(opts.Method))
+	}
+	var result PriorityList
+	path := "/api/census/" + strconv.Itoa(censusYear) + "/priority-list"
+	if err := self.getJSON(ctx, path, query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Proposals returns the Houses that the given rules for the size of the
+// House would have produced after each census.  No rules means the standard
+// ones.
+func (self *Client) Proposals(ctx context.Context,
+	rules []proposals.Rule) (*Proposals, error) {
+
+	query := make(url.Values)
+	if len(rules) > 0 {
+		names := make([]string, len(rules))
+		for i, rule := range rules {
+			names[i] = rule.String()
+		}
+		query.Set("rules", strings.Join(names, ","))
+	}
+	var result Proposals
+	if err := self.getJSON(ctx, "/api/proposals", query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Forecast returns the apportionment after the census in the given year,
+// for the current size of the House and for the given rules.  No rules means
+// the standard ones.
+func (self *Client) Forecast(ctx context.Context, year int,
+	rules []proposals.Rule) (*Forecast, error) {
+
+	query := make(url.Values)
+	if len(rules) > 0 {
+		names := make([]string, len(rules))
+		for i, rule := range rules {
+			names[i] = rule.String()
+		}
+		query.Set("rules", strings.Join(names, ","))
+	}
+	var result Forecast
+	path := "/api/forecast/" + strconv.Itoa(year)
+	if err := self.getJSON(ctx, path, query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CongressStats returns statistics about the given congress.
+func (self *Client) CongressStats(ctx context.Context, congress int) (*CongressStats, error) {
+	var result CongressStats
+	if err := self.getJSON(ctx, congressPath(congress)+"/stats", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Inequality returns measures of how unequal representation is in the given
+// congress.
+func (self *Client) Inequality(ctx context.Context,
+	congress int) (*CongressInequality, error) {
+
+	var result CongressInequality
+	if err := self.getJSON(ctx, congressPath(congress)+"/inequality", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AllStats returns statistics about every congress for which we have any.
+func (self *Client) AllStats(ctx context.Context) (map[int]*CongressStats, error) {
+	var result map[int]*CongressStats
+	err := self.getJSON(ctx, "/api/stats", nil, &result)
+	return result, err
+}
+
+// Compare compares two congresses.  limit is the number of districts whose
+// turnout changed the most to return (0 means the API's default).
+func (self *Client) Compare(ctx context.Context, from, to int,
+	limit int) (*Comparison, error) {
+
+	query := url.Values{
+		"from": {strconv.Itoa(from)},
+		"to":   {strconv.Itoa(to)},
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var result Comparison
+	if err := self.getJSON(ctx, "/api/compare", query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Locate returns the district (or, failing that, the state) in the given
+// congress that contains the given point.
+func (self *Client) Locate(ctx context.Context, congress int,
+	lat, lon float64) (*Location, error) {
+
+	query := url.Values{
+		"lat": {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"lon": {strconv.FormatFloat(lon, 'f', -1, 64)},
+	}
+	var result Location
+	path := congressPath(congress) + "/locate"
+	if err := self.getJSON(ctx, path, query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
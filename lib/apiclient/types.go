@@ -0,0 +1,288 @@
+package apiclient
+
+import (
+	"expandourhouse.com/lib/apportionment"
+)
+
+type Congress struct {
+	Nbr       int
+	StartYear int
+}
+
+// Fact is a fact about a district, such as its turnout or its population.
+// Estimates (e.g., from the ACS) have margins of error; counts don't.
+//
+// A turnout's Value and Source are the preferred source's, and Sources has
+// every source's value.  Disagreement is true if the sources' values differ
+// by more than the API allows.
+type Fact struct {
+	Value         int     `json:"value"`
+	Source        string  `json:"source"`
+	MarginOfError *int    `json:"marginOfError,omitempty"` /* nil == exact */
+	Sources       []*Fact `json:"sources,omitempty"`
+	Disagreement  bool    `json:"disagreement,omitempty"`
+}
+
+// DistrictFacts maps names of facts (e.g., "turnout") to the facts.
+type DistrictFacts map[string]*Fact
+
+// Turnout returns the district's turnout, or nil if unknown.
+func (self DistrictFacts) Turnout() *Fact {
+	return self["turnout"]
+}
+
+// Aggregate is a number about a state, derived from its districts' facts.
+// Sums of estimates have margins of error; sums of counts don't.
+type Aggregate struct {
+	Value         float64  `json:"value"`
+	MarginOfError *float64 `json:"marginOfError"` /* nil == exact */
+	Sources       []string `json:"sources"`
+	NbrDistricts  int      `json:"nbrDistricts"` /* that have the fact */
+	Complete      bool     `json:"complete"`     /* all districts have the fact */
+}
+
+// StateAggregates are a state's totals.  nil means unknown.
+type StateAggregates struct {
+	NbrReps      int        `json:"nbrReps"`
+	Population   *Aggregate `json:"population"`
+	Adults       *Aggregate `json:"adults"`
+	Citizens     *Aggregate `json:"citizens"`
+	CVAP         *Aggregate `json:"cvap"`
+	Turnout      *Aggregate `json:"turnout"`
+	PeoplePerRep *Aggregate `json:"peoplePerRep"`
+}
+
+type State struct {
+	// IrregularHow lists the ways in which the state is irregular.  Irregular
+	// states have no districts (but do have aggregates).
+	IrregularHow []string              `json:"irregularHow"`
+	Districts    map[int]DistrictFacts `json:"districts"`
+	Aggregates   *StateAggregates      `json:"aggregates"`
+
+	// Apportionment is the state's part of an apportionment (nil except in
+	// an Apportionment).
+	Apportionment *StateApportionment `json:"apportionment"`
+}
+
+func (self *State) Irregular() bool {
+	return len(self.IrregularHow) > 0
+}
+
+type Representative struct {
+	BioguideID string  `json:"bioguideId"`
+	FirstName  *string `json:"firstName"`
+	MiddleName *string `json:"middleName"`
+	LastName   *string `json:"lastName"`
+	Party      *string `json:"party"`
+	District   *int    `json:"district"`  /* nil == unknown; 0 == at-large */
+	StartDate  string  `json:"startDate"` /* YYYY-MM-DD */
+	EndDate    string  `json:"endDate"`
+}
+
+type OverlappingTerms struct {
+	District int             `json:"district"`
+	First    *Representative `json:"first"`
+	Second   *Representative `json:"second"`
+}
+
+type Irregularity struct {
+	Type        string `json:"type"`
+	Explanation string `json:"explanation"`
+
+	// evidence (depending on the type)
+	OverlappingTerms []*OverlappingTerms `json:"overlappingTerms"`
+	UnknownTerms     []*Representative   `json:"unknownDistrictTerms"`
+	AtLargeTerms     []*Representative   `json:"atLargeTerms"`
+	NumberedTerms    []*Representative   `json:"numberedDistrictTerms"`
+}
+
+type StateIrregularities struct {
+	Irregular      bool            `json:"irregular"`
+	Irregularities []*Irregularity `json:"irregularities"`
+}
+
+type StateApportionment struct {
+	Population   int     `json:"population"`
+	Seats        int     `json:"seats"`
+	ActualSeats  int     `json:"actualSeats"`
+	Change       int     `json:"change"`
+	PeoplePerRep float64 `json:"peoplePerRep"`
+}
+
+type Apportionment struct {
+	Size             int                  `json:"size"`
+	Method           apportionment.Method `json:"method"`
+	PopulationSource string               `json:"populationSource"`
+	Tied             []string             `json:"tied"`
+	States           map[string]*State    `json:"states"`
+}
+
+// ApportionmentOptions are the options for reapportioning the House.  Zero
+// values mean the API's defaults.
+type ApportionmentOptions struct {
+	Size   int /* 0 == the size of the census's actual apportionment */
+	Method apportionment.Method
+}
+
+type PrioritySeat struct {
+	HouseSize int     `json:"houseSize"` /* once this seat is assigned */
+	State     string  `json:"state"`
+	StateSeat int     `json:"stateSeat"` /* the state's seats once it gets this one */
+	Priority  float64 `json:"priority"`
+
+	// NearCutoff is "lastIn" or "firstOut" for seats near the actual House
+	// size, and "" for the others.
+	NearCutoff string `json:"nearCutoff"`
+}
+
+// PriorityList is the order in which the seats beyond each state's first
+// are assigned.
+type PriorityList struct {
+	CensusYear       int                  `json:"censusYear"`
+	Method           apportionment.Method `json:"method"`
+	PopulationSource string               `json:"populationSource"`
+	ActualSize       *int                 `json:"actualSize"` /* nil == unknown */
+	Through          int                  `json:"through"`
+	Tied             []string             `json:"tied"`
+	Seats            []*PrioritySeat      `json:"seats"`
+	LastIn           []*PrioritySeat      `json:"lastIn"`
+	FirstOut         []*PrioritySeat      `json:"firstOut"`
+}
+
+// PriorityListOptions are the options for a priority list.  Zero values mean
+// the API's defaults.
+type PriorityListOptions struct {
+	Through int /* 0 == a few seats past the actual size */
+	Method  apportionment.Method
+}
+
+type SizeComparison struct {
+	Congress   int `json:"congress"`
+	ActualSize int `json:"actualSize"`
+	Difference int `json:"difference"` /* proposed minus actual */
+}
+
+// Proposal is the House that a rule for its size produces after a census.
+type Proposal struct {
+	Size        int               `json:"size"`
+	Seats       map[string]int    `json:"seats"`
+	Comparisons []*SizeComparison `json:"comparisons"`
+}
+
+type CensusProposals struct {
+	PopulationSource string               `json:"populationSource"`
+	Proposals        map[string]*Proposal `json:"proposals"` /* by rule name */
+}
+
+type Proposals struct {
+	Rules    map[string]string        `json:"rules"` /* names -> descriptions */
+	Censuses map[int]*CensusProposals `json:"censuses"`
+}
+
+type ForecastState struct {
+	Population   int  `json:"population"`
+	Seats        int  `json:"seats"`
+	CurrentSeats *int `json:"currentSeats"` /* nil == unknown */
+	Change       *int `json:"change"`
+}
+
+// ForecastScenario is the House that a rule produces with one scenario's
+// projected populations.
+type ForecastScenario struct {
+	PopulationSource string                    `json:"populationSource"`
+	Size             int                       `json:"size"`
+	States           map[string]*ForecastState `json:"states"`
+	LastIn           []*PrioritySeat           `json:"lastIn"`
+	FirstOut         []*PrioritySeat           `json:"firstOut"`
+}
+
+type SeatsAtRisk struct {
+	State        string         `json:"state"`
+	CurrentSeats *int           `json:"currentSeats"`
+	Seats        map[string]int `json:"seats"`  /* by scenario */
+	LastIn       []string       `json:"lastIn"` /* scenarios */
+}
+
+type ForecastHouse struct {
+	Description string                       `json:"description"`
+	Scenarios   map[string]*ForecastScenario `json:"scenarios"`
+	AtRisk      []*SeatsAtRisk               `json:"atRisk"`
+}
+
+// Forecast is the apportionment after a future census, for each scenario
+// of the projected populations.
+type Forecast struct {
+	Year          int                       `json:"year"`
+	FirstCongress int                       `json:"firstCongress"`
+	BaseCensus    *int                      `json:"baseCensus"` /* nil == unknown */
+	CurrentSize   int                       `json:"currentSize"`
+	Scenarios     []string                  `json:"scenarios"`
+	Houses        map[string]*ForecastHouse `json:"houses"` /* "current" and rule names */
+}
+
+type CongressStats struct {
+	NbrReps          *int               `json:"nbrReps"`
+	MedianVoters     *float64           `json:"medianVoters"`
+	MinVoters        *float64           `json:"minVoters"`
+	MaxVoters        *float64           `json:"maxVoters"`
+	MeanVoters       *float64           `json:"meanVoters"`
+	VoterPercentiles map[string]float64 `json:"voterPercentiles"`
+}
+
+// InequalityIndices measure how unequal representation is (see package
+// inequality).
+type InequalityIndices struct {
+	NbrDistricts   int     `json:"nbrDistricts"`
+	MaxMinRatio    float64 `json:"maxMinRatio"`
+	Gini           float64 `json:"gini"`
+	LoosemoreHanby float64 `json:"loosemoreHanby"`
+	MajorityShare  float64 `json:"majorityShare"`
+}
+
+// CongressInequality has the inequality indices for a congress's regular
+// districts, by turnout and by population.  nil means unknown.
+type CongressInequality struct {
+	Turnout    *InequalityIndices `json:"turnout"`
+	Population *InequalityIndices `json:"population"`
+	Adults     *InequalityIndices `json:"adults"`
+	Citizens   *InequalityIndices `json:"citizens"`
+	CVAP       *InequalityIndices `json:"cvap"`
+}
+
+type SeatChange struct {
+	From   int `json:"from"`
+	To     int `json:"to"`
+	Change int `json:"change"`
+}
+
+type MedianVotersChange struct {
+	From   *float64 `json:"from"` /* nil == unknown */
+	To     *float64 `json:"to"`
+	Change *float64 `json:"change"`
+}
+
+type TurnoutChange struct {
+	State    string `json:"state"`
+	District int    `json:"district"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Change   int    `json:"change"`
+}
+
+type Comparison struct {
+	From           int                    `json:"from"`
+	To             int                    `json:"to"`
+	Seats          map[string]*SeatChange `json:"seats"`
+	StatesAdded    []string               `json:"statesAdded"`
+	StatesRemoved  []string               `json:"statesRemoved"`
+	MedianVoters   MedianVotersChange     `json:"medianVoters"`
+	TurnoutChanges []*TurnoutChange       `json:"turnoutChanges"`
+}
+
+type Location struct {
+	State        string        `json:"state"`
+	District     *int          `json:"district"` /* nil == not in a known district */
+	ShapeID      *string       `json:"shapeId"`
+	IrregularHow []string      `json:"irregularHow"`
+	Facts        DistrictFacts `json:"facts"`
+}
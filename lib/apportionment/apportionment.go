@@ -0,0 +1,237 @@
+// Package apportionment divides the seats of the House of Representatives
+// among the states according to their populations.
+//
+// Every method gives each state at least one seat, as the Constitution
+// requires.  The divisor methods (Huntington-Hill, Webster, Jefferson,
+// Adams, and Dean) then hand out the remaining seats one at a time to the
+// state with the highest priority value; Hamilton/Vinton gives each state
+// the whole part of its quota (or its one seat) and hands out the leftover
+// seats in order of largest remainder.
+package apportionment
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Seat is one seat assigned beyond the constitutional minimum.
+type Seat struct {
+	// Nbr is the size of the House once this seat has been assigned.
+	Nbr int
+
+	State string
+
+	// Priority is the state's priority value for this seat.
+	Priority float64
+}
+
+// Result is the outcome of an apportionment.
+type Result struct {
+	Method Method
+	Size   int
+
+	// Seats maps each state to its number of seats.
+	Seats map[string]int
+
+	// Assignments lists the seats beyond the constitutional minimum in the
+	// order in which they were assigned.  It is nil for Hamilton/Vinton,
+	// which does not assign seats in order.
+	Assignments []Seat
+
+	// Tied lists the states, in alphabetical order, that were tied for the
+	// last seat(s).  When it is not empty, the seats were split among the
+	// tied states alphabetically, which is arbitrary.
+	Tied []string
+}
+
+// Apportion divides size seats among the states in pops, which maps each
+// state to its apportionment population.
+func Apportion(pops map[string]int, size int, method Method) (*Result, error) {
+	if len(pops) == 0 {
+		return nil, errors.New("No states to apportion among")
+	}
+	if size < len(pops) {
+		return nil, fmt.Errorf("House size %v is less than the number of states (%v)",
+			size, len(pops))
+	}
+	for state, pop := range pops {
+		if pop <= 0 {
+			return nil, fmt.Errorf("Population of %v must be positive", state)
+		}
+	}
+	if _, err := ParseMethod(string(method)); err != nil {
+		return nil, err
+	}
+
+	if method.IsDivisorMethod() {
+		return apportionByDivisor(pops, size, method), nil
+	}
+	return apportionByRemainder(pops, size), nil
+}
+
+func sortedStates(pops map[string]int) []string {
+	var states []string
+	for state := range pops {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	return states
+}
+
+type claim struct {
+	state    string
+	seats    int
+	priority float64
+}
+
+type claimHeap []*claim
+
+func (self claimHeap) Len() int { return len(self) }
+
+func (self claimHeap) Less(i, j int) bool {
+	if self[i].priority != self[j].priority {
+		return self[i].priority > self[j].priority
+	}
+	return self[i].state < self[j].state
+}
+
+func (self claimHeap) Swap(i, j int) { self[i], self[j] = self[j], self[i] }
+
+func (self *claimHeap) Push(x interface{}) { *self = append(*self, x.(*claim)) }
+
+func (self *claimHeap) Pop() interface{} {
+	old := *self
+	c := old[len(old)-1]
+	*self = old[:len(old)-1]
+	return c
+}
+
+func apportionByDivisor(pops map[string]int, size int, method Method) *Result {
+	result := &Result{Method: method, Size: size, Seats: make(map[string]int)}
+
+	// give each state its first seat
+	var claims claimHeap
+	for _, state := range sortedStates(pops) {
+		result.Seats[state] = 1
+		claims = append(claims, &claim{
+			state:    state,
+			seats:    1,
+			priority: PriorityValue(method, pops[state], 1),
+		})
+	}
+	heap.Init(&claims)
+
+	// hand out the rest in order of priority
+	for nbr := len(pops) + 1; nbr <= size; nbr++ {
+		c := claims[0]
+		result.Assignments = append(result.Assignments,
+			Seat{Nbr: nbr, State: c.state, Priority: c.priority})
+		c.seats++
+		result.Seats[c.state] = c.seats
+		c.priority = PriorityValue(method, pops[c.state], c.seats)
+		heap.Fix(&claims, 0)
+	}
+
+	// look for a tie across the cutoff
+	if len(result.Assignments) > 0 && claims[0].priority ==
+		result.Assignments[len(result.Assignments)-1].Priority {
+
+		cutoff := claims[0].priority
+		tied := make(map[string]bool)
+		for _, seat := range result.Assignments {
+			if seat.Priority == cutoff {
+				tied[seat.State] = true
+			}
+		}
+		for _, c := range claims {
+			if c.priority == cutoff {
+				tied[c.state] = true
+			}
+		}
+		result.Tied = sortedKeys(tied)
+	}
+
+	return result
+}
+
+func apportionByRemainder(pops map[string]int, size int) *Result {
+	result := &Result{Method: HamiltonVinton, Size: size, Seats: make(map[string]int)}
+
+	var total int64
+	for _, pop := range pops {
+		total += int64(pop)
+	}
+
+	/*
+		As under the Vinton Act, the quotas are computed over the whole
+		House.  A state whose quota is less than one still gets a seat,
+		which comes out of the seats left for the largest remainders, and
+		it has no claim to any more.
+	*/
+	type remainder struct {
+		state string
+		value int64 // numerator of the fractional part, over total
+	}
+	var remainders []remainder
+	assigned := 0
+	for _, state := range sortedStates(pops) {
+		n := int64(pops[state]) * int64(size)
+		whole := int(n / total)
+		if whole == 0 {
+			result.Seats[state] = 1
+			assigned++
+			continue
+		}
+		result.Seats[state] = whole
+		assigned += whole
+		remainders = append(remainders, remainder{state, n % total})
+	}
+
+	// hand out the rest by largest remainder
+	sort.SliceStable(remainders, func(i, j int) bool {
+		return remainders[i].value > remainders[j].value
+	})
+	extra := size - assigned
+	for i := 0; i < extra; i++ {
+		result.Seats[remainders[i].state]++
+	}
+
+	/*
+		In the (never seen) case where the states below one quota take
+		more seats than were left, the states with the smallest
+		remainders give seats back.
+	*/
+	cutoff := extra
+	for i := len(remainders) - 1; i >= 0 && cutoff < 0; i-- {
+		if result.Seats[remainders[i].state] > 1 {
+			result.Seats[remainders[i].state]--
+			cutoff++
+		}
+	}
+
+	// look for a tie across the cutoff
+	if extra > 0 && extra < len(remainders) &&
+		remainders[extra-1].value == remainders[extra].value {
+
+		tied := make(map[string]bool)
+		for _, r := range remainders {
+			if r.value == remainders[extra].value {
+				tied[r.state] = true
+			}
+		}
+		result.Tied = sortedKeys(tied)
+	}
+
+	return result
+}
+
+func sortedKeys(m map[string]bool) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
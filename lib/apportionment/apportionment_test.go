@@ -0,0 +1,217 @@
+package apportionment
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+/*
+testdata/official.csv has, for each census, the populations by which the
+states were apportioned and the seats that the method gave each of them
+(before any seats that Congress added on top, e.g., in 1862 and 1872).
+Before 1870, the populations are the federal numbers (the free population
+plus three fifths of the enslaved) computed from the census counts.  From
+1870 through 1980, they are the resident populations; in some censuses
+those differ slightly from the apportionment populations (e.g., "Indians
+not taxed" were excluded until 1940), but not by enough to change any
+seats.  From 1990 on, they are the apportionment populations.
+*/
+
+/*
+For these censuses, the populations that could be transcribed don't
+reproduce the official seats, so the apportionment populations are still
+needed.
+*/
+var gUntranscribedCensuses = map[int]string{
+	1820: "The federal numbers give South Carolina 10 seats and Alabama 2, not 9 and 3",
+	1890: "The resident populations give Minnesota 8 seats and Virginia 9, not 7 and 10",
+	1900: "The resident populations give New York, Pennsylvania, Maine, and Nebraska " +
+		"38, 33, 3, and 5 seats, not 37, 32, 4, and 6",
+	1930: "The resident populations give Arizona and New Mexico 2 seats each, not 1",
+	1970: "The resident populations give Connecticut 7 seats and Oklahoma 5, not 6 and 6",
+}
+
+type officialApportionment struct {
+	pops  map[string]int
+	seats map[string]int
+}
+
+func readOfficial(t *testing.T) map[int]*officialApportionment {
+	f, err := os.Open("testdata/official.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	recs, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[int]*officialApportionment)
+	for _, rec := range recs[1:] {
+		var nbrs [3]int
+		for i, s := range []string{rec[0], rec[2], rec[3]} {
+			if nbrs[i], err = strconv.Atoi(s); err != nil {
+				t.Fatalf("Bad record %v: %v", rec, err)
+			}
+		}
+		year, state := nbrs[0], rec[1]
+		official, ok := result[year]
+		if !ok {
+			official = &officialApportionment{make(map[string]int), make(map[string]int)}
+			result[year] = official
+		}
+		official.pops[state] = nbrs[1]
+		official.seats[state] = nbrs[2]
+	}
+	return result
+}
+
+func TestHistoricalApportionments(t *testing.T) {
+	officials := readOfficial(t)
+	for _, h := range HistoricalApportionments() {
+		h := h
+		t.Run(strconv.Itoa(h.CensusYear), func(t *testing.T) {
+			official, ok := officials[h.CensusYear]
+			if why, untranscribed := gUntranscribedCensuses[h.CensusYear]; untranscribed {
+				if ok {
+					t.Fatalf("testdata has the %v census, which is marked untranscribed",
+						h.CensusYear)
+				}
+				t.Skip(why)
+			}
+			if !ok {
+				t.Fatalf("No official populations for %v in testdata", h.CensusYear)
+			}
+			res, err := Apportion(official.pops, h.Size, h.Method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for state, want := range official.seats {
+				if got := res.Seats[state]; got != want {
+					t.Errorf("%v: got %v seats; want %v", state, got, want)
+				}
+			}
+			if len(res.Tied) > 0 {
+				t.Errorf("Unexpected tie: %v", res.Tied)
+			}
+		})
+	}
+}
+
+func TestPriorityValue(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		method Method
+		pop    int
+		n      int
+		want   float64
+	}{
+		{HuntingtonHill, 1000, 0, inf},
+		{HuntingtonHill, 1000, 1, 1000 / math.Sqrt2},
+		{HuntingtonHill, 1000, 2, 1000 / math.Sqrt(6)},
+		{Webster, 1000, 0, 2000},
+		{Webster, 1000, 2, 400},
+		{Jefferson, 1000, 0, 1000},
+		{Jefferson, 1000, 1, 500},
+		{Adams, 1000, 0, inf},
+		{Adams, 1000, 2, 500},
+		{Dean, 1000, 0, inf},
+		{Dean, 1000, 1, 750},
+	}
+	for _, c := range cases {
+		got := PriorityValue(c.method, c.pop, c.n)
+		if math.Abs(got-c.want) > 1e-9 && !(math.IsInf(got, 1) && math.IsInf(c.want, 1)) {
+			t.Errorf("PriorityValue(%v, %v, %v) = %v; want %v", c.method, c.pop, c.n,
+				got, c.want)
+		}
+	}
+}
+
+func TestTies(t *testing.T) {
+	cases := []struct {
+		name   string
+		pops   map[string]int
+		size   int
+		method Method
+		seats  map[string]int
+		tied   []string
+	}{
+		{
+			name:   "divisor tie",
+			pops:   map[string]int{"A": 100, "B": 100},
+			size:   3,
+			method: HuntingtonHill,
+			seats:  map[string]int{"A": 2, "B": 1},
+			tied:   []string{"A", "B"},
+		},
+		{
+			name:   "divisor tie across seat counts",
+			pops:   map[string]int{"A": 200, "B": 100},
+			size:   5,
+			method: Jefferson,
+			seats:  map[string]int{"A": 4, "B": 1},
+			tied:   []string{"A", "B"},
+		},
+		{
+			name:   "no divisor tie",
+			pops:   map[string]int{"A": 200, "B": 100},
+			size:   3,
+			method: HuntingtonHill,
+			seats:  map[string]int{"A": 2, "B": 1},
+		},
+		{
+			name:   "remainder tie",
+			pops:   map[string]int{"A": 100, "B": 100, "C": 100},
+			size:   4,
+			method: HamiltonVinton,
+			seats:  map[string]int{"A": 2, "B": 1, "C": 1},
+			tied:   []string{"A", "B", "C"},
+		},
+		{
+			name:   "no remainder tie",
+			pops:   map[string]int{"A": 260, "B": 140, "C": 100},
+			size:   5,
+			method: HamiltonVinton,
+			seats:  map[string]int{"A": 3, "B": 1, "C": 1},
+		},
+	}
+	for _, c := range cases {
+		res, err := Apportion(c.pops, c.size, c.method)
+		if err != nil {
+			t.Errorf("%v: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(res.Seats, c.seats) {
+			t.Errorf("%v: got seats %v; want %v", c.name, res.Seats, c.seats)
+		}
+		if !reflect.DeepEqual(res.Tied, c.tied) {
+			t.Errorf("%v: got tied %v; want %v", c.name, res.Tied, c.tied)
+		}
+	}
+}
+
+func TestApportionErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		pops   map[string]int
+		size   int
+		method Method
+	}{
+		{"no states", map[string]int{}, 10, HuntingtonHill},
+		{"size less than states", map[string]int{"A": 100, "B": 100, "C": 100}, 2, HuntingtonHill},
+		{"size less than states (Hamilton)", map[string]int{"A": 100, "B": 100}, 1, HamiltonVinton},
+		{"zero population", map[string]int{"A": 100, "B": 0}, 5, HuntingtonHill},
+		{"negative population", map[string]int{"A": 100, "B": -5}, 5, Webster},
+		{"unknown method", map[string]int{"A": 100, "B": 100}, 5, Method("borda")},
+	}
+	for _, c := range cases {
+		if res, err := Apportion(c.pops, c.size, c.method); err == nil {
+			t.Errorf("%v: got %v; want an error", c.name, res.Seats)
+		}
+	}
+}
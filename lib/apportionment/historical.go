@@ -0,0 +1,87 @@
+package apportionment
+
+// HistoricalApportionment describes how the House was actually apportioned
+// after a census.
+type HistoricalApportionment struct {
+	CensusYear int
+	Method     Method
+
+	// Size is the number of seats to which the method was applied.  It
+	// does not include seats that Congress later granted on top of the
+	// method's result (e.g., the supplementary seats of 1862 and 1872) or
+	// the seats given to states admitted during the decade.
+	Size int
+}
+
+/*
+No apportionment was made after the 1920 census; the 1910 apportionment
+stayed in effect until the 1930 census.
+
+Before 1850, Congress fixed a ratio of people per representative rather than
+a House size.  The sizes below are the ones those ratios produced.
+*/
+var gHistoricalApportionments = []HistoricalApportionment{
+	{1790, Jefferson, 105},
+	{1800, Jefferson, 141},
+	{1810, Jefferson, 181},
+	{1820, Jefferson, 213},
+	{1830, Jefferson, 240},
+	{1840, Webster, 223},
+	{1850, HamiltonVinton, 233},
+	{1860, HamiltonVinton, 233},
+	{1870, HamiltonVinton, 283},
+	{1880, HamiltonVinton, 325},
+	{1890, HamiltonVinton, 356},
+	{1900, HamiltonVinton, 386},
+	{1910, Webster, 433},
+	{1930, Webster, 435},
+	{1940, HuntingtonHill, 435},
+	{1950, HuntingtonHill, 435},
+	{1960, HuntingtonHill, 435},
+	{1970, HuntingtonHill, 435},
+	{1980, HuntingtonHill, 435},
+	{1990, HuntingtonHill, 435},
+	{2000, HuntingtonHill, 435},
+	{2010, HuntingtonHill, 435},
+	{2020, HuntingtonHill, 435},
+}
+
+// HistoricalApportionments returns the actual apportionments, in order of
+// census year.
+func HistoricalApportionments() []HistoricalApportionment {
+	return append([]HistoricalApportionment(nil), gHistoricalApportionments...)
+}
+
+// Historical returns the actual apportionment made after the given census,
+// if there was one.
+func Historical(censusYear int) (HistoricalApportionment, bool) {
+	for _, h := range gHistoricalApportionments {
+		if h.CensusYear == censusYear {
+			return h, true
+		}
+	}
+	return HistoricalApportionment{}, false
+}
+
+// FirstCongress returns the number of the first Congress whose House was
+// apportioned according to the given census.  It was elected two years
+// after the census.
+func FirstCongress(censusYear int) int {
+	return (censusYear+3-1789)/2 + 1
+}
+
+// CensusForCongress returns the year of the census according to which the
+// House of the given Congress was apportioned.  It returns false for the
+// 1st and 2nd Congresses, whose House was apportioned by the Constitution
+// itself.  Congresses after the last known apportionment are assumed to
+// still be governed by it.
+func CensusForCongress(congress int) (int, bool) {
+	year, ok := 0, false
+	for _, h := range gHistoricalApportionments {
+		if FirstCongress(h.CensusYear) > congress {
+			break
+		}
+		year, ok = h.CensusYear, true
+	}
+	return year, ok
+}
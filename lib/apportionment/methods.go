@@ -0,0 +1,100 @@
+package apportionment
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Method is a way of dividing the seats of the House among the states.
+type Method string
+
+const (
+	// HuntingtonHill is the method of equal proportions, used since the
+	// 1940 census.
+	HuntingtonHill Method = "huntington-hill"
+
+	// Webster is the method of major fractions, used after the 1840, 1910,
+	// and 1930 censuses.
+	Webster Method = "webster"
+
+	// Jefferson is the method of greatest divisors, used after the 1790
+	// through 1830 censuses.
+	Jefferson Method = "jefferson"
+
+	// HamiltonVinton is the largest-remainder method, used after the 1850
+	// through 1900 censuses.
+	HamiltonVinton Method = "hamilton"
+
+	// Adams is the method of smallest divisors.  It was proposed but never
+	// used.
+	Adams Method = "adams"
+
+	// Dean is the method of harmonic means.  It was proposed but never used.
+	Dean Method = "dean"
+)
+
+var gMethods = []Method{HuntingtonHill, Webster, Jefferson, HamiltonVinton,
+	Adams, Dean}
+
+// Methods returns all the supported methods.
+func Methods() []Method {
+	return append([]Method(nil), gMethods...)
+}
+
+// ParseMethod returns the method with the given name.  Names are
+// case-insensitive, and "vinton" and "hamilton-vinton" are accepted as
+// aliases for HamiltonVinton.
+func ParseMethod(s string) (Method, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "vinton", "hamilton-vinton":
+		return HamiltonVinton, nil
+	}
+	for _, m := range gMethods {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown apportionment method: %q", s)
+}
+
+// IsDivisorMethod returns whether the method assigns seats one by one in
+// order of priority value (as opposed to by largest remainder).
+func (self Method) IsDivisorMethod() bool {
+	return self != HamiltonVinton
+}
+
+// divisor returns the divisor used to compute a state's claim to its
+// (n+1)th seat, given that it already has n seats.
+func (self Method) divisor(n int) float64 {
+	f := float64(n)
+	switch self {
+	case HuntingtonHill:
+		return math.Sqrt(f * (f + 1))
+	case Webster:
+		return f + 0.5
+	case Jefferson:
+		return f + 1
+	case Adams:
+		return f
+	case Dean:
+		return f * (f + 1) / (f + 0.5)
+	}
+	panic(fmt.Sprintf("%v is not a divisor method", self))
+}
+
+// PriorityValue returns the claim that a state with the given population
+// has to its (n+1)th seat, given that it already has n seats.  Higher
+// values are assigned first.  For the methods whose divisor is zero for
+// n == 0, the claim is infinite.
+func PriorityValue(method Method, pop int, n int) float64 {
+	if n < 0 {
+		panic("n must be non-negative")
+	}
+	d := method.divisor(n)
+	if d == 0 {
+		return math.Inf(1)
+	}
+	return float64(pop) / d
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"expandourhouse.com/lib/apiclient"
+)
+
+const gUSAGE = `usage: eoh [OPTIONS] COMMAND ARGS...
+
+Commands:
+  congresses                        List the congresses
+  congress CONGRESS                 Show a congress's states and stats
+  state CONGRESS STATE              Show a state's districts and representatives
+  district CONGRESS STATE DISTRICT  Show a district's facts and representatives
+  priority-list CENSUS_YEAR         Show the order in which the seats are assigned
+  forecast CENSUS_YEAR              Show the seats at risk after a future census
+
+Options:
+`
+
+// gDefaultAPIURL is where local-dev runs the API.
+const gDefaultAPIURL = "http://localhost:8081"
+
+type command struct {
+	nbrArgs int
+	run     func(ctx context.Context, client *apiclient.Client, args []string) (interface{}, error)
+}
+
+var gCommands = map[string]command{
+	"congresses":    {0, runCongresses},
+	"congress":      {1, runCongress},
+	"state":         {2, runState},
+	"district":      {3, runDistrict},
+	"priority-list": {1, runPriorityList},
+	"forecast":      {1, runForecast},
+}
+
+var gJSON bool
+var gOut = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+func usage() {
+	os.Stderr.WriteString(gUSAGE)
+	flag.PrintDefaults()
+}
+
+func main() {
+	// get args
+	apiURL := os.Getenv("EOH_API_URL")
+	if len(apiURL) == 0 {
+		apiURL = gDefaultAPIURL
+	}
+	flag.StringVar(&apiURL, "api", apiURL, "URL of the API server (env: EOH_API_URL)")
+	flag.BoolVar(&gJSON, "json", false, "Print JSON instead of tables")
+	timeout := flag.Duration("timeout", 30*time.Second, "Give up after this long")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(1)
+	}
+	cmd, ok := gCommands[flag.Arg(0)]
+	if !ok || flag.NArg()-1 != cmd.nbrArgs {
+		usage()
+		os.Exit(1)
+	}
+	if gJSON {
+		/* The commands print tables as they go, so throw them away */
+		gOut.Init(ioutil.Discard, 0, 4, 2, ' ', 0)
+	}
+
+	// run command
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	result, err := cmd.run(ctx, apiclient.New(apiURL), flag.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if gJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+		return
+	}
+	gOut.Flush()
+}
+
+func parseInt(name, s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%v must be a number: %v", name, s)
+	}
+	return n, nil
+}
+
+func formatFact(f *apiclient.Fact) string {
+	if f == nil {
+		return "?"
+	}
+	if f.MarginOfError != nil {
+		return fmt.Sprintf("%v ± %v", f.Value, *f.MarginOfError)
+	}
+	if f.Disagreement {
+		return fmt.Sprintf("%v (sources disagree)", f.Value)
+	}
+	return strconv.Itoa(f.Value)
+}
+
+func formatAggregate(a *apiclient.Aggregate) string {
+	if a == nil {
+		return "?"
+	}
+	result := strconv.FormatFloat(a.Value, 'f', 0, 64)
+	if a.MarginOfError != nil {
+		result += " ± " + strconv.FormatFloat(*a.MarginOfError, 'f', 0, 64)
+	}
+	if !a.Complete {
+		result += " (incomplete)"
+	}
+	return result
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return "?"
+	}
+	return strconv.FormatFloat(*f, 'f', 0, 64)
+}
+
+func formatName(rep *apiclient.Representative) string {
+	var parts []string
+	for _, part := range []*string{rep.FirstName, rep.MiddleName, rep.LastName} {
+		if part != nil {
+			parts = append(parts, *part)
+		}
+	}
+	if len(parts) == 0 {
+		return rep.BioguideID
+	}
+	return strings.Join(parts, " ")
+}
+
+func printReps(reps []*apiclient.Representative) {
+	fmt.Fprintf(gOut, "\nDISTRICT\tREPRESENTATIVE\tPARTY\tSTART\tEND\n")
+	for _, rep := range reps {
+		district, party := "?", ""
+		if rep.District != nil {
+			district = strconv.Itoa(*rep.District)
+		}
+		if rep.Party != nil {
+			party = *rep.Party
+		}
+		fmt.Fprintf(gOut, "%v\t%v\t%v\t%v\t%v\n", district, formatName(rep), party,
+			rep.StartDate, rep.EndDate)
+	}
+}
+
+func runCongresses(ctx context.Context, client *apiclient.Client,
+	args []string) (interface{}, error) {
+
+	cons, err := client.Congresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(gOut, "CONGRESS\tSTART YEAR\n")
+	for _, con := range cons {
+		fmt.Fprintf(gOut, "%v\t%v\n", con.Nbr, con.StartYear)
+	}
+	return cons, nil
+}
+
+func runCongress(ctx context.Context, client *apiclient.Client,
+	args []string) (interface{}, error) {
+
+	congress, err := parseInt("CONGRESS", args[0])
+	if err != nil {
+		return nil, err
+	}
+	states, err := client.States(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := client.CongressStats(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(gOut, "Median voters per district:\t%v\n", formatFloat(stats.MedianVoters))
+	fmt.Fprintf(gOut, "Mean voters per district:\t%v\n\n", formatFloat(stats.MeanVoters))
+	fmt.Fprintf(gOut, "STATE\tDISTRICTS\tTURNOUT\tPEOPLE PER REP\tIRREGULAR\n")
+	var names []string
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		state := states[name]
+		var turnout, perRep *apiclient.Aggregate
+		if state.Aggregates != nil {
+			turnout, perRep = state.Aggregates.Turnout, state.Aggregates.PeoplePerRep
+		}
+		fmt.Fprintf(gOut, "%v\t%v\t%v\t%v\t%v\n", name, len(state.Districts),
+			formatAggregate(turnout), formatAggregate(perRep),
+			strings.Join(state.IrregularHow, ", "))
+	}
+	return map[string]interface{}{"states": states, "stats": stats}, nil
+}
+
+func runState(ctx context.Context, client *apiclient.Client,
+	args []string) (interface{}, error) {
+
+	congress, err := parseInt("CONGRESS", args[0])
+	if err != nil {
+		return nil, err
+	}
+	stateAbbr := strings.ToUpper(args[1])
+	states, err := client.States(ctx, congress)
+	if err != nil {
+		return nil, err
+	}
+	state, ok := states[stateAbbr]
+	if !ok {
+		return nil, fmt.Errorf("No such state in congress %v: %v", congress, stateAbbr)
+	}
+	reps, err := client.StateRepresentatives(ctx, congress, stateAbbr)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Irregular() {
+		irregularities, err := client.StateIrregularities(ctx, congress, stateAbbr)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(gOut, "%v is irregular:\n", stateAbbr)
+		for _, irregularity := range irregularities.Irregularities {
+			fmt.Fprintf(gOut, "  %v\n", irregularity.Explanation)
+		}
+	} else {
+		fmt.Fprintf(gOut, "DISTRICT\tTURNOUT\n")
+		var nbrs []int
+		for nbr := range state.Districts {
+			nbrs = append(nbrs, nbr)
+		}
+		sort.Ints(nbrs)
+		for _, nbr := range nbrs {
+			fmt.Fprintf(gOut, "%v\t%v\n", nbr, formatFact(state.Districts[nbr].Turnout()))
+		}
+	}
+	printReps(reps)
+	return map[string]interface{}{"state": state, "representatives": reps}, nil
+}
+
+func runDistrict(ctx context.Context, client *apiclient.Client,
+	args []string) (interface{}, error) {
+
+	congress, err := parseInt("CONGRESS", args[0])
+	if err != nil {
+		return nil, err
+	}
+	stateAbbr := strings.ToUpper(args[1])
+	district, err := parseInt("DISTRICT", args[2])
+	if err != nil {
+		return nil, err
+	}
+	facts, err := client.District(ctx, congress, stateAbbr, district)
+	if err != nil {
+		return nil, err
+	}
+	reps, err := client.DistrictRepresentatives(ctx, congress, stateAbbr, district)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(gOut, "FACT\tVALUE\tSOURCE\n")
+	var names []string
+	for name := range facts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(gOut, "%v\t%v\t%v\n", name, formatFact(facts[name]), facts[name].Source)
+	}
+	printReps(reps)
+	return map[string]interface{}{"facts": facts, "representatives": reps}, nil
+}
+
+func runPriorityList(ctx context.Context, client *apiclient.Client,
+	args []string) (interface{}, error) {
+
+	censusYear, err := parseInt("CENSUS_YEAR", args[0])
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.PriorityList(ctx, censusYear, apiclient.PriorityListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(gOut, "HOUSE SIZE\tSTATE\tSTATE'S SEATS\tPRIORITY\t\n")
+	for _, seat := range list.Seats {
+		fmt.Fprintf(gOut, "%v\t%v\t%v\t%.0f\t%v\n", seat.HouseSize, seat.State,
+			seat.StateSeat, seat.Priority, seat.NearCutoff)
+	}
+	return list, nil
+}
+
+func runForecast(ctx context.Context, client *apiclient.Client,
+	args []string) (interface{}, error) {
+
+	year, err := parseInt("CENSUS_YEAR", args[0])
+	if err != nil {
+		return nil, err
+	}
+	forecast, err := client.Forecast(ctx, year, nil)
+	if err != nil {
+		return nil, err
+	}
+	house := forecast.Houses["current"]
+	if house == nil {
+		return forecast, nil
+	}
+
+	fmt.Fprintf(gOut, "%v states with seats at risk after the %v census (%v seats)\n\n",
+		len(house.AtRisk), forecast.Year, forecast.CurrentSize)
+	fmt.Fprintf(gOut, "STATE\tCURRENT\t")
+	for _, scenario := range forecast.Scenarios {
+		fmt.Fprintf(gOut, "%v\t", strings.ToUpper(scenario))
+	}
+	fmt.Fprintf(gOut, "LAST IN\n")
+	for _, risk := range house.AtRisk {
+		current := "?"
+		if risk.CurrentSeats != nil {
+			current = strconv.Itoa(*risk.CurrentSeats)
+		}
+		fmt.Fprintf(gOut, "%v\t%v\t", risk.State, current)
+		for _, scenario := range forecast.Scenarios {
+			fmt.Fprintf(gOut, "%v\t", risk.Seats[scenario])
+		}
+		fmt.Fprintf(gOut, "%v\n", strings.Join(risk.LastIn, ", "))
+	}
+	return forecast, nil
+}
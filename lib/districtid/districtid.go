@@ -0,0 +1,36 @@
+// Package districtid parses the IDs of the district shapes in Lewis et al.'s
+// United States Congressional District Shapefiles
+// (http://cdmaps.polisci.ucla.edu), which are also the IDs of the districts
+// in our tilesets.
+//
+// An ID looks like "006108112011": a three-digit state FIPS code, the
+// numbers of the first and last congresses that had the district, and the
+// district number (0 for at-large districts).
+package districtid
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type ID struct {
+	StateFips int
+	District  int /* 0 = at-large; negative for areas with no district (e.g., Indian lands) */
+}
+
+func Parse(id string) (ID, error) {
+	var result ID
+	var err error
+	if len(id) < 12 {
+		return result, fmt.Errorf("Invalid district ID: %v", id)
+	}
+	result.StateFips, err = strconv.Atoi(id[0:3])
+	if err != nil {
+		return result, fmt.Errorf("Invalid district ID: %v: %v", id, err)
+	}
+	result.District, err = strconv.Atoi(id[10:12])
+	if err != nil {
+		return result, fmt.Errorf("Invalid district ID: %v: %v", id, err)
+	}
+	return result, nil
+}
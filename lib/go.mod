@@ -0,0 +1,3 @@
+module expandourhouse.com/lib
+
+go 1.13
@@ -0,0 +1,91 @@
+// Package inequality measures how unequal representation in the House is.
+//
+// Each measure takes the sizes of the districts (in voters or people), each
+// of which elects one representative.  If every district had the same size,
+// representation would be perfectly equal: the max/min ratio would be 1,
+// the Gini coefficient and the Loosemore-Hanby index would be 0, and a
+// majority of the House would be elected by just over half the people.
+package inequality
+
+import (
+	"errors"
+	"sort"
+)
+
+// Indices are the measures of inequality for a set of districts.
+type Indices struct {
+	NbrDistricts int
+
+	// MaxMinRatio is the size of the largest district divided by that of
+	// the smallest.
+	MaxMinRatio float64
+
+	// Gini is the Gini coefficient of the districts' sizes: 0 is perfect
+	// equality and values near 1 are extreme inequality.
+	Gini float64
+
+	// LoosemoreHanby is half the sum, over the districts, of the difference
+	// between the district's share of the seats and its share of the people.
+	// It is the fraction of seats that are "misallocated".
+	LoosemoreHanby float64
+
+	// MajorityShare is the smallest share of the people that elects a
+	// majority of the House (i.e., the share that lives in the smallest
+	// majority of the districts).
+	MajorityShare float64
+}
+
+// Compute computes the indices for districts of the given sizes, which
+// must be positive.
+func Compute(sizes []int) (*Indices, error) {
+	if len(sizes) == 0 {
+		return nil, errors.New("No districts")
+	}
+	sorted := make([]float64, len(sizes))
+	total := 0.0
+	for i, size := range sizes {
+		if size <= 0 {
+			return nil, errors.New("District sizes must be positive")
+		}
+		sorted[i] = float64(size)
+		total += float64(size)
+	}
+	sort.Float64s(sorted)
+
+	n := float64(len(sorted))
+	result := Indices{
+		NbrDistricts: len(sorted),
+		MaxMinRatio:  sorted[len(sorted)-1] / sorted[0],
+	}
+
+	/*
+		With the sizes in ascending order, and i starting at 1:
+
+			Gini = 2 * sum(i * size(i)) / (n * total) - (n + 1) / n
+	*/
+	weightedSum := 0.0
+	for i, size := range sorted {
+		weightedSum += float64(i+1) * size
+	}
+	result.Gini = 2*weightedSum/(n*total) - (n+1)/n
+
+	// compute Loosemore-Hanby
+	seatShare := 1 / n
+	for _, size := range sorted {
+		diff := seatShare - size/total
+		if diff < 0 {
+			diff = -diff
+		}
+		result.LoosemoreHanby += diff / 2
+	}
+
+	// compute majority share
+	majority := len(sorted)/2 + 1
+	majorityPeople := 0.0
+	for _, size := range sorted[:majority] {
+		majorityPeople += size
+	}
+	result.MajorityShare = majorityPeople / total
+
+	return &result, nil
+}
@@ -0,0 +1,79 @@
+package inequality
+
+import (
+	"math"
+	"testing"
+)
+
+const gEpsilon = 1e-9
+
+func TestEqualSizes(t *testing.T) {
+	for _, n := range []int{1, 2, 435} {
+		sizes := make([]int, n)
+		for i := range sizes {
+			sizes[i] = 700000
+		}
+		indices, err := Compute(sizes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantMajority := float64(n/2+1) / float64(n)
+		if indices.NbrDistricts != n || indices.MaxMinRatio != 1 ||
+			math.Abs(indices.Gini) > gEpsilon ||
+			math.Abs(indices.LoosemoreHanby) > gEpsilon ||
+			math.Abs(indices.MajorityShare-wantMajority) > gEpsilon {
+
+			t.Errorf("%v districts: got %+v", n, indices)
+		}
+	}
+
+	/* Just over half, as the package says */
+	indices, _ := Compute([]int{5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5})
+	if indices.MajorityShare <= 0.5 || indices.MajorityShare > 0.55 {
+		t.Errorf("Got majority share %v", indices.MajorityShare)
+	}
+}
+
+func TestUnequalSizes(t *testing.T) {
+	indices, err := Compute([]int{100, 100, 200, 600})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	/*
+		Gini: 2 * (100 + 200 + 600 + 2400) / (4 * 1000) - 5/4
+		Loosemore-Hanby: (0.15 + 0.15 + 0.05 + 0.35) / 2
+		Majority share: (100 + 100 + 200) / 1000
+	*/
+	want := Indices{
+		NbrDistricts:   4,
+		MaxMinRatio:    6,
+		Gini:           0.4,
+		LoosemoreHanby: 0.35,
+		MajorityShare:  0.4,
+	}
+	if indices.NbrDistricts != want.NbrDistricts {
+		t.Errorf("Got %v districts; want %v", indices.NbrDistricts, want.NbrDistricts)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"MaxMinRatio", indices.MaxMinRatio, want.MaxMinRatio},
+		{"Gini", indices.Gini, want.Gini},
+		{"LoosemoreHanby", indices.LoosemoreHanby, want.LoosemoreHanby},
+		{"MajorityShare", indices.MajorityShare, want.MajorityShare},
+	} {
+		if math.Abs(c.got-c.want) > gEpsilon {
+			t.Errorf("Got %v %v; want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestBadSizes(t *testing.T) {
+	for _, sizes := range [][]int{nil, {100, 0}, {100, -5}} {
+		if indices, err := Compute(sizes); err == nil {
+			t.Errorf("%v: got %+v; want an error", sizes, indices)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package proposals computes the Houses that proposed rules for the size of
+// the House would produce.
+//
+// Most rules fix the size of the House and leave the division of the seats
+// to the method in use since the 1940 census (Huntington-Hill).  A fixed
+// ratio instead gives each state one seat per so many people, as Congress
+// did before 1850, so the size is whatever the ratio produces.
+package proposals
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/lib/apportionment"
+)
+
+// Kind is a kind of rule.
+type Kind string
+
+const (
+	// Wyoming makes the size of the House the total population divided by
+	// the population of the smallest state, rounded.
+	Wyoming Kind = "wyoming"
+
+	// CubeRoot makes the size of the House the cube root of the total
+	// population, rounded.
+	CubeRoot Kind = "cube-root"
+
+	// Ratio gives each state one seat per Param people (rounded down), but
+	// at least one.
+	Ratio Kind = "ratio"
+
+	// FixedSize makes the size of the House Param.
+	FixedSize Kind = "size"
+)
+
+// Rule is a rule for the size of the House.
+type Rule struct {
+	Kind  Kind
+	Param int /* people per seat for Ratio; seats for FixedSize */
+}
+
+// gStandardRules are the rules that are proposed most often.
+var gStandardRules = []Rule{
+	{Wyoming, 0},
+	{CubeRoot, 0},
+	{Ratio, 30000}, /* the Congressional Apportionment Amendment's */
+	{FixedSize, 585},
+	{FixedSize, 1000},
+}
+
+// StandardRules returns the rules that are proposed most often.
+func StandardRules() []Rule {
+	return append([]Rule(nil), gStandardRules...)
+}
+
+// String returns the rule's name (e.g., "wyoming" or "ratio-30000").
+func (self Rule) String() string {
+	switch self.Kind {
+	case Ratio, FixedSize:
+		return fmt.Sprintf("%v-%v", self.Kind, self.Param)
+	default:
+		return string(self.Kind)
+	}
+}
+
+func (self Rule) MarshalText() ([]byte, error) {
+	return []byte(self.String()), nil
+}
+
+func (self *Rule) UnmarshalText(text []byte) error {
+	rule, err := ParseRule(string(text))
+	if err != nil {
+		return err
+	}
+	*self = rule
+	return nil
+}
+
+// Description describes the rule in English.
+func (self Rule) Description() string {
+	switch self.Kind {
+	case Wyoming:
+		return "As many seats as the total population divided by the smallest state's"
+	case CubeRoot:
+		return "As many seats as the cube root of the total population"
+	case Ratio:
+		return fmt.Sprintf("One seat per %v people in each state", self.Param)
+	case FixedSize:
+		return fmt.Sprintf("%v seats", self.Param)
+	}
+	return self.String()
+}
+
+// ParseRule returns the rule with the given name (see Rule.String).  Names
+// are case-insensitive.
+func ParseRule(s string) (Rule, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch Kind(s) {
+	case Wyoming, CubeRoot:
+		return Rule{Kind: Kind(s)}, nil
+	}
+	for _, kind := range []Kind{Ratio, FixedSize} {
+		prefix := string(kind) + "-"
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+		param, err := strconv.Atoi(s[len(prefix):])
+		if err != nil || param <= 0 {
+			return Rule{}, fmt.Errorf("Invalid number in House size rule: %q", s)
+		}
+		return Rule{Kind: kind, Param: param}, nil
+	}
+	return Rule{}, fmt.Errorf("Unknown House size rule: %q", s)
+}
+
+// Proposal is the House that a rule produces for some populations.
+type Proposal struct {
+	Rule  Rule
+	Size  int
+	Seats map[string]int
+}
+
+// Apply applies the rule to the given populations, which map each state to
+// its apportionment population.
+func (self Rule) Apply(pops map[string]int) (*Proposal, error) {
+	if len(pops) == 0 {
+		return nil, errors.New("No states to apportion among")
+	}
+	total, smallest := 0, 0
+	for state, pop := range pops {
+		if pop <= 0 {
+			return nil, fmt.Errorf("Population of %v must be positive", state)
+		}
+		total += pop
+		if smallest == 0 || pop < smallest {
+			smallest = pop
+		}
+	}
+
+	// get size
+	var size int
+	switch self.Kind {
+	case Wyoming:
+		size = int(math.Round(float64(total) / float64(smallest)))
+	case CubeRoot:
+		size = int(math.Round(math.Cbrt(float64(total))))
+	case FixedSize:
+		size = self.Param
+	case Ratio:
+		return self.applyRatio(pops), nil
+	default:
+		return nil, fmt.Errorf("Unknown House size rule: %v", self)
+	}
+
+	// apportion
+	res, err := apportionment.Apportion(pops, size, apportionment.HuntingtonHill)
+	if err != nil {
+		return nil, err
+	}
+	return &Proposal{Rule: self, Size: res.Size, Seats: res.Seats}, nil
+}
+
+func (self Rule) applyRatio(pops map[string]int) *Proposal {
+	result := &Proposal{Rule: self, Seats: make(map[string]int)}
+	for state, pop := range pops {
+		seats := pop / self.Param
+		if seats < 1 {
+			seats = 1
+		}
+		result.Seats[state] = seats
+		result.Size += seats
+	}
+	return result
+}
+
+// Comparison compares a proposal's size with the actual size of a
+// congress's House.
+type Comparison struct {
+	Congress   int
+	ActualSize int
+	Difference int /* proposed minus actual */
+}
+
+// Compare compares the proposal's size with the given actual sizes, which
+// map congresses to the sizes of their Houses.  The result is in order of
+// congress.
+func (self *Proposal) Compare(actualSizes map[int]int) []Comparison {
+	result := make([]Comparison, 0, len(actualSizes))
+	for congress, size := range actualSizes {
+		result = append(result, Comparison{
+			Congress:   congress,
+			ActualSize: size,
+			Difference: self.Size - size,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Congress < result[j].Congress })
+	return result
+}
@@ -0,0 +1,83 @@
+package proposals
+
+import (
+	"reflect"
+	"testing"
+)
+
+var gTestPops = map[string]int{
+	"AA": 1000000,
+	"BB": 2500000,
+	"CC": 500000,
+}
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		rule      Rule
+		wantSize  int
+		wantSeats map[string]int /* nil == don't check */
+	}{
+		/* 4,000,000 / 500,000 */
+		{Rule{Kind: Wyoming}, 8, map[string]int{"AA": 2, "BB": 5, "CC": 1}},
+
+		/* cube root of 4,000,000 is 158.7 */
+		{Rule{Kind: CubeRoot}, 159, nil},
+
+		{Rule{Kind: Ratio, Param: 30000}, 132, map[string]int{"AA": 33, "BB": 83, "CC": 16}},
+
+		/* CC gets its one seat */
+		{Rule{Kind: Ratio, Param: 600000}, 6, map[string]int{"AA": 1, "BB": 4, "CC": 1}},
+
+		{Rule{Kind: FixedSize, Param: 10}, 10, map[string]int{"AA": 3, "BB": 6, "CC": 1}},
+	}
+	for _, c := range cases {
+		proposal, err := c.rule.Apply(gTestPops)
+		if err != nil {
+			t.Errorf("%v: %v", c.rule, err)
+			continue
+		}
+		if proposal.Size != c.wantSize {
+			t.Errorf("%v: got size %v; want %v", c.rule, proposal.Size, c.wantSize)
+		}
+		total := 0
+		for _, seats := range proposal.Seats {
+			total += seats
+		}
+		if total != proposal.Size || len(proposal.Seats) != len(gTestPops) {
+			t.Errorf("%v: got seats %v for size %v", c.rule, proposal.Seats, proposal.Size)
+		}
+		if c.wantSeats != nil && !reflect.DeepEqual(proposal.Seats, c.wantSeats) {
+			t.Errorf("%v: got seats %v; want %v", c.rule, proposal.Seats, c.wantSeats)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	cases := []struct {
+		rule Rule
+		pops map[string]int
+	}{
+		{Rule{Kind: FixedSize, Param: 2}, gTestPops}, /* fewer seats than states */
+		{Rule{Kind: Wyoming}, map[string]int{"AA": 1000000, "BB": 0}},
+		{Rule{Kind: CubeRoot}, map[string]int{}},
+	}
+	for _, c := range cases {
+		if proposal, err := c.rule.Apply(c.pops); err == nil {
+			t.Errorf("%v on %v: got %+v; want an error", c.rule, c.pops, proposal)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	for _, rule := range StandardRules() {
+		parsed, err := ParseRule(rule.String())
+		if err != nil || parsed != rule {
+			t.Errorf("%v: got %v, %v", rule, parsed, err)
+		}
+	}
+	for _, s := range []string{"", "wyoming-1", "ratio", "ratio-0", "size--5", "senate"} {
+		if rule, err := ParseRule(s); err == nil {
+			t.Errorf("%q: got %v; want an error", s, rule)
+		}
+	}
+}
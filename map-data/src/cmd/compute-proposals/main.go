@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/lib/apportionment"
+	"expandourhouse.com/lib/proposals"
+	"expandourhouse.com/mapdata/congresses"
+	"expandourhouse.com/mapdata/housedb"
+)
+
+/*
+For each census in the given CSV file (with columns census_year, state, and
+population), and for each rule for the size of the House:
+	- The size of the House
+	- The seats of each state
+	- The difference from the actual size of each congress's House
+*/
+
+const gUsage = "usage: compute-proposals [-rules RULE,...] CENSUS_POPS_CSV\n"
+
+// readPops reads the states' populations, by census year.
+func readPops(path string) map[int]map[string]int {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	header, err := reader.Read()
+	if err != nil {
+		log.Fatalf("%v: %v", path, err)
+	}
+	colToIdx := make(map[string]int)
+	for i, col := range header {
+		colToIdx[strings.TrimSpace(col)] = i
+	}
+	for _, col := range []string{"census_year", "state", "population"} {
+		if _, ok := colToIdx[col]; !ok {
+			log.Fatalf("%v: missing column: %v", path, col)
+		}
+	}
+
+	result := make(map[int]map[string]int)
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("%v: %v", path, err)
+		}
+		year, err := strconv.Atoi(rec[colToIdx["census_year"]])
+		if err != nil {
+			log.Fatalf("%v: invalid census year: %v", path, rec[colToIdx["census_year"]])
+		}
+		pop, err := strconv.Atoi(rec[colToIdx["population"]])
+		if err != nil {
+			log.Fatalf("%v: invalid population: %v", path, rec[colToIdx["population"]])
+		}
+		if _, ok := result[year]; !ok {
+			result[year] = make(map[string]int)
+		}
+		result[year][rec[colToIdx["state"]]] = pop
+	}
+	return result
+}
+
+func parseRules(s string) []proposals.Rule {
+	if len(s) == 0 {
+		return proposals.StandardRules()
+	}
+	var result []proposals.Rule
+	for _, part := range strings.Split(s, ",") {
+		rule, err := proposals.ParseRule(part)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+func main() {
+	log.SetOutput(os.Stderr)
+	ctx := context.Background()
+
+	// parse args
+	rulesFlag := flag.String("rules", "", "Comma-separated rules (default: the standard ones)")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		os.Stderr.WriteString(gUsage)
+		os.Exit(1)
+	}
+	rules := parseRules(*rulesFlag)
+	pops := readPops(flag.Arg(0))
+
+	// connect to DB
+	db := housedb.Connect(ctx)
+	defer db.Close()
+
+	// get actual sizes, by census
+	actualSizes := make(map[int]map[int]int)
+	for _, cong := range congresses.GetUpTo(db.LastCongress(ctx)) {
+		year, ok := apportionment.CensusForCongress(cong.Number)
+		if !ok {
+			continue
+		}
+		if nbrReps := db.NbrReps(ctx, cong.Number); nbrReps > 10 { // weed out implausible numbers
+			if _, ok := actualSizes[year]; !ok {
+				actualSizes[year] = make(map[int]int)
+			}
+			actualSizes[year][cong.Number] = nbrReps
+		}
+	}
+
+	// apply rules
+	var years []int
+	for year := range pops {
+		years = append(years, year)
+	}
+	sort.Ints(years)
+	ruleDescs := make(map[string]string)
+	for _, rule := range rules {
+		ruleDescs[rule.String()] = rule.Description()
+	}
+	censuses := make(map[string]interface{})
+	for _, year := range years {
+		census := make(map[string]interface{})
+		for _, rule := range rules {
+			proposal, err := rule.Apply(pops[year])
+			if err != nil {
+				log.Fatalf("%v census: %v", year, err)
+			}
+			comparisons := []map[string]int{}
+			for _, c := range proposal.Compare(actualSizes[year]) {
+				comparisons = append(comparisons, map[string]int{
+					"congress":   c.Congress,
+					"actualSize": c.ActualSize,
+					"difference": c.Difference,
+				})
+			}
+			census[rule.String()] = map[string]interface{}{
+				"size":        proposal.Size,
+				"seats":       proposal.Seats,
+				"comparisons": comparisons,
+			}
+		}
+		censuses[fmt.Sprintf("%v", year)] = map[string]interface{}{"proposals": census}
+	}
+
+	// write
+	data := map[string]interface{}{"rules": ruleDescs, "censuses": censuses}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		panic(err)
+	}
+}
@@ -26,7 +26,7 @@ func main() {
 	defer db.Close()
 
 	data := make(map[string]map[string]interface{})
-	for _, cong := range congresses.GetAll() {
+	for _, cong := range congresses.GetUpTo(db.LastCongress(ctx)) {
 		stats := make(map[string]interface{})
 		if nbrReps := db.NbrReps(ctx, cong.Number); nbrReps > 10 { // weed out implausible numbers
 			stats["nbrReps"] = nbrReps
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"expandourhouse.com/mapdata/states"
+)
+
+/*
+Reads the Census Bureau's historical apportionment data (apportionment.csv)
+from stdin and writes the states' populations as CSV with the columns
+census_year, state, and population, which compute-proposals reads.
+
+The Census Bureau's file has the resident populations, which differ
+slightly from the apportionment populations (e.g., by overseas federal
+employees).  DC, Puerto Rico, and the regions and nation are skipped.
+*/
+
+const gUsage = "usage: extract-census-pops < apportionment.csv\n"
+
+const gMaxStateFips = 56
+
+func main() {
+	log.SetOutput(os.Stderr)
+
+	// parse args
+	flag.Parse()
+	if flag.NArg() != 0 {
+		os.Stderr.WriteString(gUsage)
+		os.Exit(1)
+	}
+
+	// read header
+	reader := csv.NewReader(os.Stdin)
+	header, err := reader.Read()
+	if err != nil {
+		log.Fatal(err)
+	}
+	colToIdx := make(map[string]int)
+	for i, col := range header {
+		colToIdx[strings.TrimSpace(col)] = i
+	}
+	for _, col := range []string{"Name", "Geography Type", "Year", "Resident Population"} {
+		if _, ok := colToIdx[col]; !ok {
+			log.Fatalf("Missing column: %v", col)
+		}
+	}
+
+	writer := csv.NewWriter(os.Stdout)
+	writer.Write([]string{"census_year", "state", "population"})
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if rec[colToIdx["Geography Type"]] != "State" {
+			continue
+		}
+
+		// get state
+		name := rec[colToIdx["Name"]]
+		state, ok := states.ByName[name]
+		if !ok {
+			log.Fatalf("Unknown state: %v", name)
+		}
+		if state.Usps == "DC" || state.Fips > gMaxStateFips {
+			continue
+		}
+
+		// get population
+		year, err := strconv.Atoi(rec[colToIdx["Year"]])
+		if err != nil {
+			log.Fatalf("Invalid year: %v", rec[colToIdx["Year"]])
+		}
+		popStr := strings.ReplaceAll(rec[colToIdx["Resident Population"]], ",", "")
+		pop, err := strconv.Atoi(popStr)
+		if err != nil {
+			log.Fatalf("Invalid population for %v in %v: %v", name, year,
+				rec[colToIdx["Resident Population"]])
+		}
+
+		writer.Write([]string{strconv.Itoa(year), state.Usps, strconv.Itoa(pop)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Fatal(err)
+	}
+}
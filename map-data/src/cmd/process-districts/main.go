@@ -8,6 +8,7 @@ import (
 	"os"
 	"strconv"
 
+	"expandourhouse.com/lib/districtid"
 	"expandourhouse.com/mapdata/states"
 	"github.com/paulmach/orb/geojson"
 	"github.com/vladimirvivien/automi/collectors"
@@ -26,11 +27,7 @@ func cleanUpProps(f *geojson.Feature, congress int) *geojson.Feature {
 	if !ok {
 		log.Panic("Feature doesn't have ID")
 	}
-	stateFips, err := strconv.Atoi(id[0:3])
-	if err != nil {
-		log.Panic(err)
-	}
-	district, err := strconv.Atoi(id[10:12])
+	parsedId, err := districtid.Parse(id)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -38,10 +35,10 @@ func cleanUpProps(f *geojson.Feature, congress int) *geojson.Feature {
 	// clean up feature's properties
 	f.Properties = map[string]interface{}{
 		"id":        id,
-		"district":  district,
+		"district":  parsedId.District,
 		"congress":  congress,
-		"stateFips": stateFips,
-		"state":     states.ByFips[stateFips].Usps,
+		"stateFips": parsedId.StateFips,
+		"state":     states.ByFips[parsedId.StateFips].Usps,
 		"group":     "boundary",
 		"type":      "district",
 	}
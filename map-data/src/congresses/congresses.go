@@ -22,11 +22,11 @@ type Congress struct {
 var gCache map[int]*Congress
 
 const gFirstCongressStartYear = 1789
-const gLastCongress = 115
 
-func GetAll() []*Congress {
+// GetUpTo returns info about the first through the nth Congresses.
+func GetUpTo(n int) []*Congress {
 	var array []*Congress
-	for i := 1; i <= gLastCongress; i++ {
+	for i := 1; i <= n; i++ {
 		array = append(array, Get(i))
 	}
 	return array
@@ -3,6 +3,7 @@ module expandourhouse.com/mapdata
 go 1.13
 
 require (
+	expandourhouse.com/lib v0.0.0
 	github.com/aws/aws-sdk-go v1.32.6
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/mattn/go-sqlite3 v2.0.3+incompatible
@@ -15,3 +16,5 @@ require (
 	gopkg.in/yaml.v2 v2.3.0
 	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776 // indirect
 )
+
+replace expandourhouse.com/lib => ../../lib
@@ -175,6 +175,18 @@ func (self *Db) Close() {
 	self.tx = nil
 }
 
+// LastCongress returns the number of the last Congress that has
+// representatives in the DB.
+func (self *Db) LastCongress(ctx context.Context) int {
+	var nbr sql.NullInt64
+	err := self.db.QueryRowContext(ctx,
+		"SELECT MAX(congress_nbr) FROM representative_term").Scan(&nbr)
+	if err != nil {
+		panic(err)
+	}
+	return int(nbr.Int64)
+}
+
 func (self *Db) NbrReps(ctx context.Context, congress int) int {
 	if congress >= 61 {
 		return 435
@@ -1,4 +1,6 @@
 GO_LIB_SOURCES := \
+	../lib/go.mod \
+	$(shell find ../lib -name "*.go") \
 	$(wildcard src/bulkInserter/*.go) \
 	$(wildcard src/congresses/*.go) \
 	$(wildcard src/housedb/reps/*.go) \
@@ -16,8 +18,10 @@ GO_LIB_SOURCES := \
 _PROGRAMS = \
 	add-district-pop \
 	add-labels \
+	compute-proposals \
 	compute-stats \
 	congress-start-year \
+	extract-census-pops \
 	extract-states-for-year \
 	make-style \
 	mark-irregular \
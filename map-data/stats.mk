@@ -2,4 +2,25 @@ APP_DIR = ${PWD}/../app
 STATS = ${APP_DIR}/src/stats.js
 
 ${APP_DIR}/src/stats.js: ${COMP_STATS}
-	"${COMP_STATS}" > "$@"
\ No newline at end of file
+	"${COMP_STATS}" > "$@"
+
+# The Census Bureau's historical apportionment data (1910 on)
+CENSUS_APPORTIONMENT_URL = https://www2.census.gov/programs-surveys/decennial/2020/data/apportionment/apportionment.csv
+
+# CSV file with the columns census_year, state, and population
+CENSUS_POPS = ${TMP}/census-state-pops.csv
+
+${DOWNLOADS}/apportionment.csv:
+	@echo DOWNLOAD apportionment.csv
+	@mkdir -p "${DOWNLOADS}"
+	@curl --fail-early --fail "${CENSUS_APPORTIONMENT_URL}" > "$@"
+
+${CENSUS_POPS}: ${DOWNLOADS}/apportionment.csv ${EXTRACT_CENSUS_POPS}
+	@mkdir -p "${TMP}"
+	"${EXTRACT_CENSUS_POPS}" < "$<" > "$@"
+
+PROPOSALS = ${OUTPUT}/proposals.json
+
+${PROPOSALS}: ${COMP_PROPOSALS} ${CENSUS_POPS}
+	@mkdir -p "${OUTPUT}"
+	"${COMP_PROPOSALS}" "${CENSUS_POPS}" > "$@"